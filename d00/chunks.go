@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+
+	"d00/stats"
+)
+
+// chunkSize is how many bytes of plain input a worker parses at a time,
+// cut back to the last full line. Chunks are cut the same way whatever
+// -workers is and merged in input order, so the number of workers never
+// changes a result. Int and decimal data merge exactly, giving what a
+// single pass does whatever the size of the input; float data does so for
+// input smaller than a chunk.
+const chunkSize = 4 << 20
+
+// chunk is a run of whole lines; first is the number of its first line.
+type chunk struct {
+	index int
+	first int
+	data  []byte
+}
+
+// chunkResult is what a worker makes of a chunk. rejected holds the bad
+// lines in order, and err a line the scanner could not read.
+type chunkResult struct {
+	index    int
+	acc      *stats.Accumulator
+	accepted int
+	rejected []*lineError
+	err      error
+}
+
+// readPlain adds every value of one input to all of accs, which share
+// cfg.options. Chunks are parsed by cfg.workers goroutines and merged in
+// order, where the error policy is applied line by line as a single pass
+// would.
+func readPlain(path string, cfg *config, summary *readSummary, accs []*stats.Accumulator) error {
+	input, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	done := make(chan struct{})
+	defer close(done)
+	chunks := make(chan chunk)
+	results := make(chan chunkResult)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(chunks)
+		readErr <- splitChunks(input, chunks, done)
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				select {
+				case results <- parseChunk(c, fileLabel(path), cfg.options):
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	pending := make(map[int]chunkResult)
+	next := 0
+	for result := range results {
+		pending[result.index] = result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := mergeChunk(result, path, cfg, summary, accs); err != nil {
+				return err
+			}
+		}
+	}
+	return inputError(path, <-readErr)
+}
+
+// splitChunks cuts input into chunks until it ends or done is closed.
+func splitChunks(input io.Reader, chunks chan<- chunk, done <-chan struct{}) error {
+	line := 1
+	var carry []byte
+	for index := 0; ; index++ {
+		buf := make([]byte, chunkSize)
+		copy(buf, carry)
+		n, err := io.ReadFull(input, buf[len(carry):])
+		data := buf[:len(carry)+n]
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return err
+		}
+		carry = nil
+		if cut := bytes.LastIndexByte(data, '\n'); !last && cut >= 0 {
+			carry = data[cut+1:]
+			data = data[:cut+1]
+		}
+		if len(data) != 0 {
+			select {
+			case chunks <- chunk{index: index, first: line, data: data}:
+			case <-done:
+				return nil
+			}
+		}
+		line += bytes.Count(data, []byte{'\n'})
+		if last {
+			return nil
+		}
+	}
+}
+
+func parseChunk(c chunk, file string, opts stats.Options) chunkResult {
+	result := chunkResult{index: c.index}
+	// opts were already checked when the targets were created.
+	result.acc, _ = stats.New(opts)
+	scanner := bufio.NewScanner(bytes.NewReader(c.data))
+	for line := c.first; scanner.Scan(); line++ {
+		if err := result.acc.AddString(scanner.Text()); err != nil {
+			result.rejected = append(result.rejected, &lineError{file: file, line: line, err: err})
+			continue
+		}
+		result.accepted++
+	}
+	result.err = scanner.Err()
+	return result
+}
+
+// mergeChunk applies the error policy to the bad lines of a chunk and adds
+// it to every target.
+func mergeChunk(result chunkResult, path string, cfg *config, summary *readSummary, accs []*stats.Accumulator) error {
+	for _, lineErr := range result.rejected {
+		if err := summary.reject(cfg, lineErr); err != nil {
+			return err
+		}
+	}
+	summary.accepted += result.accepted
+	for _, acc := range accs {
+		if err := acc.Merge(result.acc); err != nil {
+			return err
+		}
+	}
+	return inputError(path, result.err)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"d00/stats"
+)
+
+// compareConfig is set by -a and -b, the files holding the two datasets.
+type compareConfig struct {
+	a, b string
+}
+
+// runCompare reads both datasets and prints the metrics of each side by
+// side with the change from a to b, followed by the two-sample tests.
+func runCompare(cfg *config) (readSummary, error) {
+	var summary readSummary
+	accs := make([]*stats.Accumulator, 0, 2)
+	for _, path := range []string{cfg.compare.a, cfg.compare.b} {
+		acc, fileSummary, err := readFile(path, cfg)
+		summary.accepted += fileSummary.accepted
+		summary.rejected += fileSummary.rejected
+		if err != nil {
+			return summary, err
+		}
+		accs = append(accs, acc)
+	}
+	resultsA, err := accs[0].Compute(cfg.metrics...)
+	if err != nil {
+		return summary, fmt.Errorf("%s: %w", cfg.compare.a, err)
+	}
+	resultsB, err := accs[1].Compute(cfg.metrics...)
+	if err != nil {
+		return summary, fmt.Errorf("%s: %w", cfg.compare.b, err)
+	}
+	resultsA, resultsB = exactResults(accs[0], resultsA), exactResults(accs[1], resultsB)
+	tests, err := stats.Compare(accs[0], accs[1])
+	if err != nil {
+		return summary, err
+	}
+	if cfg.format == "text" {
+		printComparisonText(resultsA, resultsB, tests)
+		return summary, nil
+	}
+	metrics := make(list, 0, len(resultsA))
+	for i := range resultsA {
+		metrics = append(metrics, object{
+			{"metric", stringScalar(resultsA[i].Name)},
+			{"a", resultScalar(resultsA[i])},
+			{"b", resultScalar(resultsB[i])},
+			{"delta", resultScalar(delta(resultsA[i], resultsB[i]))},
+		})
+	}
+	testList := make(list, 0, len(tests))
+	for _, test := range tests {
+		entry := object{{"test", stringScalar(test.Name)}, {"statistic", floatScalar(test.Statistic)}}
+		if test.DF != 0 {
+			entry = append(entry, field{"df", floatScalar(test.DF)})
+		}
+		testList = append(testList, append(entry, field{"p", floatScalar(test.PValue)}))
+	}
+	formats[cfg.format](os.Stdout, object{
+		{"a", stringScalar(cfg.compare.a)},
+		{"b", stringScalar(cfg.compare.b)},
+		{"metrics", metrics},
+		{"tests", testList},
+	})
+	return summary, nil
+}
+
+// readFile reads one plain dataset from a file, or from stdin for "-".
+func readFile(path string, cfg *config) (*stats.Accumulator, readSummary, error) {
+	var acc *stats.Accumulator
+	summary, err := read_data([]string{path}, cfg, func(_ string, data *dataset) error {
+		acc = data.columns[0].acc
+		return nil
+	})
+	return acc, summary, err
+}
+
+// delta is b - a, exact when both are.
+func delta(a, b stats.Result) stats.Result {
+	result := stats.Result{Name: a.Name, Label: a.Label, Value: b.Value - a.Value}
+	if a.Exact != nil && b.Exact != nil {
+		result.Exact = new(big.Rat).Sub(b.Exact, a.Exact)
+	}
+	return result
+}
+
+func printComparisonText(resultsA, resultsB []stats.Result, tests []stats.Test) {
+	fmt.Println("Metric\tA\tB\tDelta")
+	for i := range resultsA {
+		fmt.Printf("%s\t%s\t%s\t%s\n", resultsA[i].Label,
+			textResult(resultsA[i]), textResult(resultsB[i]), textResult(delta(resultsA[i], resultsB[i])))
+	}
+	for _, test := range tests {
+		fmt.Printf("%s: %s = %s", test.Label, test.Symbol, textFloat(test.Statistic))
+		if test.DF != 0 {
+			fmt.Printf(", df = %s", textFloat(test.DF))
+		}
+		fmt.Printf(", p = %s\n", textFloat(test.PValue))
+	}
+}
@@ -1,178 +1,460 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
-	"math"
+	"math/big"
 	"os"
-	"sort"
+	"runtime"
 	"strconv"
+	"strings"
+
+	"d00/stats"
 )
 
+// Exit codes: data errors are told apart from usage errors so scripts can
+// tell a bad dataset from a bad command line.
 const (
-	flagMean = 1 << iota
-	flagMedian
-	flagMode
-	flagSD
-	flagAll = 1<<iota - 1
+	exitData  = 1
+	exitUsage = 2
 )
 
+// combinedFile names the dataset of all inputs together for -per-file.
+const combinedFile = "*"
+
+type config struct {
+	options stats.Options
+	metrics []stats.Metric
+	format  string
+	modes   bool
+	top     int
+	// hist is set by -hist to the -bins rule.
+	hist stats.Binning
+	// ci is set by -ci.
+	ci      *ciConfig
+	onError string
+	// table is set for delimited input.
+	table *tableConfig
+	// window is set for the rolling -window mode.
+	window *windowConfig
+	// outliers is set for the -outliers mode.
+	outliers *outlierConfig
+	// compare is set by -a and -b.
+	compare *compareConfig
+	// inputs are the files to read, "-" for stdin.
+	inputs []string
+	// perFile reports every input on its own before all of them together.
+	perFile bool
+	// workers parse chunks of plain input concurrently.
+	workers int
+}
+
 func main() {
-	userFlags := flagParsing()
-	if userFlags == 0 {
+	cfg := flagParsing()
+	if cfg == nil {
 		fmt.Println("Flag error")
-		os.Exit(2)
+		os.Exit(exitUsage)
 	}
-	data := read_data()
-	if len(data) == 0 {
-		fmt.Println("Input error")
-		os.Exit(2)
+	if cfg.window != nil || cfg.outliers != nil || cfg.compare != nil {
+		summary, err := runMode(cfg)
+		if err != nil {
+			fmt.Println("Invalid input:", err)
+			os.Exit(exitData)
+		}
+		if cfg.onError != onErrorFail {
+			summary.print()
+		}
+		if cfg.onError == onErrorReport && summary.rejected != 0 {
+			os.Exit(exitData)
+		}
+		return
 	}
-	sort.Slice(data, func(i, j int) bool {
-		return data[i] < data[j]
+	var printErr error
+	printed := 0
+	summary, err := read_data(cfg.inputs, cfg, func(key string, data *dataset) error {
+		if printed > 0 && cfg.format == "text" {
+			fmt.Println()
+		}
+		printed++
+		printErr = printDataset(key, data, cfg)
+		return printErr
 	})
-	printMetrics(data, userFlags)
+	if printErr != nil {
+		fmt.Println("Input error")
+		os.Exit(exitData)
+	}
+	if err != nil {
+		fmt.Println("Invalid input:", err)
+		fmt.Println("Input error")
+		os.Exit(exitData)
+	}
+	if cfg.onError != onErrorFail {
+		summary.print()
+	}
+	if cfg.onError == onErrorReport && summary.rejected != 0 {
+		os.Exit(exitData)
+	}
 }
 
-func truncateToHundreds(f float64) float64 {
-	return math.Round(f*100) / 100
+// runMode runs -a and -b, -outliers or -window, the modes reading a single
+// dataset from a single input.
+func runMode(cfg *config) (readSummary, error) {
+	if cfg.compare != nil {
+		return runCompare(cfg)
+	}
+	input, err := openInput(cfg.inputs[0])
+	if err != nil {
+		return readSummary{}, err
+	}
+	defer input.Close()
+	if cfg.outliers != nil {
+		return runOutliers(input, cfg)
+	}
+	return runWindow(input, cfg)
 }
 
-func printMetrics(data []int32, userFlags int32) {
-	if userFlags&flagMean != 0 {
-		mean := getMean(data)
-		if mean-math.Round(mean) == 0 {
-			fmt.Printf("Mean: %.1f\n", mean)
-		} else {
-			mean = truncateToHundreds(mean)
-			fmt.Println("Mean:", mean)
+// printDataset prints a single report for plain input, and one block per
+// column plus the pairwise statistics for delimited input. With -group-by
+// it is called once per group, and key is printed first. With -per-file
+// it is called once per file and once more for all of them, and the file
+// is printed first, "*" for all of them.
+func printDataset(key string, data *dataset, cfg *config) error {
+	reports := make([]report, 0, len(data.columns))
+	for _, col := range data.columns {
+		rep, err := buildReport(col.acc, cfg)
+		if err != nil {
+			return err
 		}
+		reports = append(reports, rep)
 	}
-	if userFlags&flagMedian != 0 {
-		median := getMedian(data)
-		if median-math.Round(median) == 0 {
-			fmt.Printf("Median: %.1f\n", median)
-		} else {
-			median = truncateToHundreds(median)
-			fmt.Println("Median:", median)
+	// labels tell which file and group the dataset is for, when there is
+	// more than one.
+	var labels object
+	if cfg.perFile {
+		file := data.file
+		if file == "" {
+			file = combinedFile
 		}
+		labels = append(labels, field{"file", stringScalar(file)})
 	}
-	if userFlags&flagMode != 0 {
-		fmt.Println("Mode:", getMode(data))
+	if cfg.table != nil && cfg.table.groupBy != "" {
+		labels = append(labels, field{"group", stringScalar(key)})
 	}
-	if userFlags&flagSD != 0 {
-		sd := getSD(data)
-		if sd-math.Round(sd) == 0 {
-			fmt.Printf("SD: %.1f\n", sd)
+	if cfg.format == "text" {
+		for _, label := range labels {
+			name := label.key
+			if name == "group" {
+				name = cfg.table.groupBy
+			}
+			fmt.Printf("[%s=%s]\n", name, label.value.(scalar).text)
+		}
+	}
+	if cfg.table == nil {
+		if cfg.format == "text" {
+			reports[0].printText()
 		} else {
-			sd = truncateToHundreds(sd)
-			fmt.Println("SD:", sd)
+			formats[cfg.format](os.Stdout, append(labels, reports[0].object()...))
+		}
+		return nil
+	}
+	if cfg.format == "text" {
+		for i, rep := range reports {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("[%s]\n", data.columns[i].name)
+			rep.printText()
+		}
+		for _, pair := range data.pairs {
+			fmt.Printf("\n[%s ~ %s]\n", data.columns[pair.x].name, data.columns[pair.y].name)
+			printTextValue("Covariance", stats.Covariance(pair.acc))
+			printTextValue("Pearson", stats.Pearson(pair.acc))
+			printTextValue("Spearman", stats.Spearman(pair.acc))
+		}
+		return nil
+	}
+	columns := make(list, 0, len(reports))
+	for i, rep := range reports {
+		columns = append(columns, append(object{{"column", stringScalar(data.columns[i].name)}}, rep.object()...))
+	}
+	doc := append(labels, field{"columns", columns})
+	if len(data.pairs) != 0 {
+		pairs := make(list, 0, len(data.pairs))
+		for _, pair := range data.pairs {
+			pairs = append(pairs, object{
+				{"x", stringScalar(data.columns[pair.x].name)},
+				{"y", stringScalar(data.columns[pair.y].name)},
+				{"covariance", floatScalar(stats.Covariance(pair.acc))},
+				{"pearson", floatScalar(stats.Pearson(pair.acc))},
+				{"spearman", floatScalar(stats.Spearman(pair.acc))},
+			})
 		}
+		doc = append(doc, field{"pairs", pairs})
 	}
+	formats[cfg.format](os.Stdout, doc)
+	return nil
 }
 
-func flagParsing() int32 {
-	defer func() {
-		err := recover()
+func buildReport(acc *stats.Accumulator, cfg *config) (report, error) {
+	var rep report
+	if len(cfg.metrics) != 0 {
+		results, err := acc.Compute(cfg.metrics...)
 		if err != nil {
-			fmt.Println(err)
+			return rep, err
 		}
-	}()
-	var userFlags int32 = 0
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.PanicOnError)
-	flagMeanTemp := flag.Bool("mean", false, "usage -mean")
-	flagMedianTemp := flag.Bool("median", false, "usage -median")
-	flagModeTemp := flag.Bool("mode", false, "usage -mode")
-	flagSDTemp := flag.Bool("sd", false, "usage -sd")
-	flag.Parse()
-	if *flagMeanTemp {
-		userFlags += flagMean
+		rep.results = exactResults(acc, results)
+	} else if acc.Count() == 0 {
+		return rep, stats.ErrEmpty
+	}
+	if cfg.modes {
+		modes := stats.Modes(acc)
+		rep.modes = &modes
 	}
-	if *flagMedianTemp {
-		userFlags += flagMedian
+	if cfg.top > 0 {
+		rep.top = stats.TopK(acc, cfg.top)
+	}
+	if cfg.ci != nil {
+		intervals, err := cfg.ci.compute(acc)
+		if err != nil {
+			return rep, err
+		}
+		rep.intervals = intervals
 	}
-	if *flagModeTemp {
-		userFlags += flagMode
+	if cfg.hist != nil {
+		hist, err := stats.Histogram(acc, cfg.hist)
+		if err != nil {
+			return rep, err
+		}
+		rep.hist = hist
 	}
-	if *flagSDTemp {
-		userFlags += flagSD
+	return rep, nil
+}
+
+// exactResults fills in the fractions -exact prints for the mean and
+// median, which are only exact by themselves for decimal data.
+func exactResults(acc *stats.Accumulator, results []stats.Result) []stats.Result {
+	if !outputFormat.exact {
+		return results
 	}
-	if userFlags == 0 {
-		userFlags = flagAll
+	for i, result := range results {
+		switch result.Name {
+		case "mean":
+			results[i].Exact = stats.ExactMean(acc)
+		case "median":
+			results[i].Exact = stats.ExactMedian(acc)
+		}
 	}
-	return userFlags
+	return results
 }
 
-func read_data() []int32 {
+// flagParsing exposes every registered metric as a boolean flag and selects
+// the requested ones, or the default ones when nothing at all is asked for.
+// It returns nil on a usage error.
+func flagParsing() *config {
 	defer func() {
 		err := recover()
 		if err != nil {
-			fmt.Println("Invalid input:", err)
+			fmt.Println(err)
 		}
 	}()
-	numSl := make([]int32, 0, 64)
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Text()
-		num, err := strconv.Atoi(line)
-		if err != nil {
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.PanicOnError)
+	all := stats.Metrics()
+	selected := make([]*bool, len(all))
+	for i, metric := range all {
+		selected[i] = flag.Bool(metric.Name(), false, "usage -"+metric.Name())
+	}
+	format := flag.String("format", "text", "usage -format text|json|csv|yaml")
+	percentiles := flag.String("p", "", "usage -p 5,25,75,95")
+	modes := flag.Bool("modes", false, "usage -modes")
+	top := flag.Int("top", 0, "usage -top 10")
+	hist := flag.Bool("hist", false, "usage -hist: print a histogram of the data")
+	bins := flag.String("bins", "sturges", "usage -hist -bins sturges|fd|10")
+	ci := flag.Float64("ci", 0, "usage -ci 95: confidence intervals for the mean, median and SD at this level")
+	resamples := flag.Int("resamples", 1000, "usage -ci 95 -resamples 1000: bootstrap resamples for the median and SD")
+	seed := flag.Int64("seed", 1, "usage -ci 95 -seed 1: seed of the bootstrap resamples")
+	kind := flag.String("type", "int", "usage -type int|float|decimal")
+	min := flag.String("min", strconv.Itoa(stats.MinValue), "usage -min -100000, or -min none")
+	max := flag.String("max", strconv.Itoa(stats.MaxValue), "usage -max 100000, or -max none")
+	onError := flag.String("on-error", onErrorFail,
+		"usage -on-error fail|skip|report: stop at the first bad line, ignore bad lines, or list them and exit 1")
+	delim := flag.String("delim", "", "usage -delim ',' (or tab) to read delimited columns")
+	header := flag.Bool("header", false, "usage -header: the first delimited row names the columns")
+	cols := flag.String("cols", "", "usage -cols price,qty: column names or 1-based positions")
+	corr := flag.Bool("corr", false, "usage -corr: covariance, Pearson and Spearman for each pair of columns")
+	weight := flag.String("weight", "", "usage -weight count: a column of integer weights, as in value,count data")
+	groupBy := flag.String("group-by", "", "usage -group-by region: one metrics block per value of this column")
+	sorted := flag.Bool("sorted", false, "usage -group-by region -sorted: the input is sorted by the group, print each one as it ends")
+	window := flag.Int("window", 0, "usage -window N: rolling metrics over the last N values")
+	every := flag.Int("every", 1, "usage -window N -every M: print the rolling metrics every M values")
+	outliers := flag.String("outliers", "", "usage -outliers zscore|iqr|mad: list the values outside the rule's fence")
+	threshold := flag.Float64("k", 0, "usage -outliers iqr -k 3: the rule's threshold, 3 for zscore, 1.5 for iqr and 3.5 for mad by default")
+	clean := flag.Bool("clean", false, "usage -outliers iqr -clean: write the other lines to stdout and the outliers to stderr")
+	precision := flag.Int("precision", outputFormat.precision, "usage -precision 2: decimals of every computed value")
+	rounding := flag.String("rounding", outputFormat.rounding, "usage -rounding half-even|half-up|truncate")
+	exact := flag.Bool("exact", false, "usage -exact: print the mean and median as exact fractions")
+	perFile := flag.Bool("per-file", false, "usage -per-file a.txt b.txt.gz: metrics of every file, then of all of them")
+	workers := flag.Int("workers", runtime.NumCPU(), "usage -workers 8: goroutines parsing plain input, the results do not depend on it")
+	fileA := flag.String("a", "", "usage -a old.txt -b new.txt: compare two datasets, - reads stdin")
+	fileB := flag.String("b", "", "usage -a old.txt -b new.txt: compare two datasets, - reads stdin")
+	flag.Parse()
+	if !validFormat(*format) {
+		panic("unknown output format " + *format)
+	}
+	if *top < 0 {
+		panic("-top must not be negative")
+	}
+	if *precision < 0 || *precision > maxPrecision {
+		panic(fmt.Sprintf("-precision must be in range [0:%d]", maxPrecision))
+	}
+	if !roundingModes[*rounding] {
+		panic("unknown rounding mode " + *rounding)
+	}
+	outputFormat = numberFormat{precision: *precision, rounding: *rounding, exact: *exact}
+	if !errorPolicies[*onError] {
+		panic("unknown error policy " + *onError)
+	}
+	cfg := &config{format: *format, modes: *modes, top: *top, onError: *onError, perFile: *perFile, workers: *workers}
+	if *workers < 1 {
+		panic("-workers must be positive")
+	}
+	var err error
+	if cfg.inputs, err = expandInputs(flag.Args()); err != nil {
+		panic(err)
+	}
+	if cfg.options.Kind, err = stats.ParseKind(*kind); err != nil {
+		panic(err)
+	}
+	if *hist {
+		if cfg.hist, err = stats.ParseBinning(*bins); err != nil {
 			panic(err)
 		}
-		if num < -100000 || num > 100000 {
-			panic("Value must be in range [-100000:100000]")
+	}
+	if *ci != 0 {
+		if *ci <= 0 || *ci >= 100 || *resamples < 1 {
+			panic("-ci must be in range (0:100) and -resamples positive")
 		}
-		numSl = append(numSl, int32(num))
+		cfg.ci = &ciConfig{level: *ci / 100, bootstrap: stats.BootstrapOptions{Resamples: *resamples, Seed: *seed}}
 	}
-	if err := scanner.Err(); err != nil {
-		panic(err)
+	cfg.options.Min = parseBound("min", *min)
+	cfg.options.Max = parseBound("max", *max)
+	if cfg.options.Min != nil && cfg.options.Max != nil && cfg.options.Min.Cmp(cfg.options.Max) > 0 {
+		panic("-min must not be greater than -max")
+	}
+	if *delim != "" || *header || *cols != "" || *corr || *weight != "" || *groupBy != "" {
+		cfg.table = &tableConfig{delim: ',', header: *header, corr: *corr, weight: *weight, groupBy: *groupBy, sorted: *sorted}
+		if *corr && *weight != "" {
+			panic("-corr does not support -weight")
+		}
+		if *sorted && *groupBy == "" {
+			panic("-sorted only works with -group-by")
+		}
+		if *delim != "" {
+			if cfg.table.delim, err = parseDelim(*delim); err != nil {
+				panic(err)
+			}
+		}
+		if *cols != "" {
+			for _, name := range strings.Split(*cols, ",") {
+				cfg.table.columns = append(cfg.table.columns, strings.TrimSpace(name))
+			}
+		}
 	}
-	return numSl
+	for i, metric := range all {
+		if *selected[i] {
+			cfg.metrics = append(cfg.metrics, metric)
+		}
+	}
+	cfg.metrics = append(cfg.metrics, parsePercentiles(*percentiles)...)
+	if len(cfg.metrics) == 0 && !cfg.modes && cfg.top == 0 && cfg.hist == nil && cfg.ci == nil {
+		cfg.metrics = stats.Defaults()
+	}
+	if *window != 0 {
+		cfg.window = &windowConfig{size: *window, every: *every}
+		if *window < 0 || *every < 1 {
+			panic("-window and -every must be positive")
+		}
+		if cfg.table != nil || cfg.modes || cfg.top != 0 || cfg.hist != nil || cfg.ci != nil {
+			panic("-window only works on plain input without -modes, -top, -hist or -ci")
+		}
+		for _, metric := range cfg.metrics {
+			if !stats.WindowMetric(metric) {
+				panic("-window supports -mean, -median, -mode and -sd, not -" + metric.Name())
+			}
+		}
+	}
+	if *outliers != "" {
+		cfg.outliers = &outlierConfig{method: *outliers, threshold: *threshold, clean: *clean}
+		if !stats.IsOutlierRule(*outliers) {
+			panic("unknown outlier rule " + *outliers)
+		}
+		if cfg.table != nil || cfg.window != nil {
+			panic("-outliers only works on plain input without -window")
+		}
+	} else if *clean || *threshold != 0 {
+		panic("-clean and -k only work with -outliers")
+	}
+	if (cfg.window != nil || cfg.outliers != nil) && (len(cfg.inputs) > 1 || cfg.perFile) {
+		panic("-window and -outliers read a single input")
+	}
+	if *fileA != "" || *fileB != "" {
+		cfg.compare = &compareConfig{a: *fileA, b: *fileB}
+		if *fileA == "" || *fileB == "" || (*fileA == "-" && *fileB == "-") {
+			panic("-a and -b must name two datasets, at most one of them -")
+		}
+		if flag.NArg() != 0 || cfg.perFile {
+			panic("-a and -b do not take other inputs or -per-file")
+		}
+		if cfg.table != nil || cfg.window != nil || cfg.outliers != nil || cfg.modes || cfg.top != 0 || cfg.hist != nil || cfg.ci != nil {
+			panic("-a and -b only work on plain input without -window, -outliers, -modes, -top, -hist or -ci")
+		}
+	}
+	return cfg
 }
 
-func getMean(slNum []int32) float64 {
-	var mean float64
-	for i := 0; i < len(slNum); i++ {
-		mean += (float64(slNum[i]) - mean) / float64((i + 1))
-	}
-	return mean
+// ciConfig is set by -ci.
+type ciConfig struct {
+	level     float64
+	bootstrap stats.BootstrapOptions
 }
 
-func getMedian(slNum []int32) float64 {
-	if len(slNum)%2 == 0 {
-		return float64(slNum[len(slNum)/2]+slNum[len(slNum)/2-1]) / 2
+// compute returns the t interval for the mean and bootstrap intervals for
+// the median and SD.
+func (ci *ciConfig) compute(acc *stats.Accumulator) ([]stats.Interval, error) {
+	metrics, err := stats.Lookup("median", "sd")
+	if err != nil {
+		return nil, err
+	}
+	intervals, err := stats.Bootstrap(acc, ci.level, ci.bootstrap, metrics...)
+	if err != nil {
+		return nil, err
 	}
-	return float64(slNum[len(slNum)/2])
+	return append([]stats.Interval{stats.MeanCI(acc, ci.level)}, intervals...), nil
 }
 
-func getMode(slNum []int32) int32 {
-	var maxStreak int32 = 1
-	var currentStreak int32 = 1
-	mode := slNum[0]
-	for i := 1; i < len(slNum); i++ {
-		if slNum[i] == slNum[i-1] {
-			currentStreak++
-		} else {
-			if currentStreak > maxStreak {
-				maxStreak = currentStreak
-				mode = slNum[i-1]
-			}
-			currentStreak = 1
-		}
+// parseBound reads a -min or -max value; "none" or an empty string leaves
+// that side of the range open.
+func parseBound(name, value string) *big.Rat {
+	if value == "" || value == "none" {
+		return nil
 	}
-	if currentStreak > maxStreak {
-		maxStreak = currentStreak
-		mode = slNum[len(slNum)-1]
+	bound, ok := new(big.Rat).SetString(value)
+	if !ok {
+		panic("-" + name + " must be a number or none: " + value)
 	}
-	return mode
+	return bound
 }
 
-func getSD(slNum []int32) float64 {
-	mean := getMean(slNum)
-	var dispersion float64
-	for i := 0; i < len(slNum); i++ {
-		dispersion += math.Pow(float64(float64(slNum[i])-mean), 2)
-	}
-	dispersion /= float64(len(slNum))
-	return math.Sqrt(dispersion)
+func parsePercentiles(list string) []stats.Metric {
+	var metrics []stats.Metric
+	if list == "" {
+		return metrics
+	}
+	for _, field := range strings.Split(list, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || p < 0 || p > 100 {
+			panic("percentile must be a number in range [0:100]: " + field)
+		}
+		metrics = append(metrics, stats.PercentileMetric(p))
+	}
+	return metrics
 }
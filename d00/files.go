@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// stdinName stands for stdin among the inputs.
+const stdinName = "-"
+
+// Magic numbers of the compressed formats d00 reads transparently.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+)
+
+// expandInputs resolves the positional arguments into the files to read,
+// in order. Glob patterns are expanded by d00 itself, so they also work
+// quoted or from a shell that does not expand them, and a pattern matching
+// nothing is an error. No arguments at all means stdin.
+func expandInputs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{stdinName}, nil
+	}
+	var paths []string
+	for _, arg := range args {
+		if arg == stdinName || !strings.ContainsAny(arg, `*?[\`) {
+			paths = append(paths, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %q", arg)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// inputStream is an opened input. Close closes the decompressor, if any,
+// and then the file.
+type inputStream struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (stream *inputStream) Close() error {
+	var first error
+	for _, closer := range stream.closers {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// openInput opens a file, or stdin for "-", and decompresses it when it
+// starts with the magic number of gzip, zstd or bzip2.
+func openInput(path string) (*inputStream, error) {
+	stream := &inputStream{}
+	var file io.Reader = os.Stdin
+	if path != stdinName {
+		opened, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		file = opened
+		stream.closers = append(stream.closers, opened)
+	}
+	buffered := bufio.NewReader(file)
+	magic := sniffMagic(buffered)
+	switch {
+	case bytes.Equal(magic, gzipMagic):
+		reader, err := gzip.NewReader(buffered)
+		if err != nil {
+			stream.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		stream.Reader = reader
+		stream.closers = append([]io.Closer{reader}, stream.closers...)
+	case bytes.Equal(magic, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			stream.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		reader := decoder.IOReadCloser()
+		stream.Reader = reader
+		stream.closers = append([]io.Closer{reader}, stream.closers...)
+	case bytes.Equal(magic, bzip2Magic):
+		stream.Reader = bzip2.NewReader(buffered)
+	default:
+		stream.Reader = buffered
+	}
+	return stream, nil
+}
+
+// sniffMagic returns the magic number input starts with, or nil. It peeks
+// one byte at a time and stops as soon as no magic number can match, so
+// plain data is told from its first byte and a live stream such as
+// tail -f is never held up waiting for more.
+func sniffMagic(input *bufio.Reader) []byte {
+	for n := 1; ; n++ {
+		head, _ := input.Peek(n)
+		if len(head) < n {
+			return nil
+		}
+		possible := false
+		for _, magic := range [][]byte{gzipMagic, zstdMagic, bzip2Magic} {
+			if bytes.Equal(head, magic) {
+				return magic
+			}
+			possible = possible || bytes.HasPrefix(magic, head)
+		}
+		if !possible {
+			return nil
+		}
+	}
+}
+
+// fileLabel is how an input is named in line errors: empty for stdin,
+// which is what d00 has always read.
+func fileLabel(path string) string {
+	if path == stdinName {
+		return ""
+	}
+	return path
+}
+
+// inputError names the input err comes from, unless it is stdin. A nil err
+// stays nil.
+func inputError(path string, err error) error {
+	if err == nil || path == stdinName {
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
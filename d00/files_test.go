@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSniffMagic(t *testing.T) {
+	tests := []struct {
+		input string
+		magic []byte
+	}{
+		{"1\n2\n", nil},
+		{"", nil},
+		{"\x1f", nil},
+		{"\x1f\x8b\x08", gzipMagic},
+		{"\x28\xb5\x2f\xfd\x00", zstdMagic},
+		{"\x28\xb5x", nil},
+		{"BZh9", bzip2Magic},
+		{"Bread,qty\n", nil},
+	}
+	for _, test := range tests {
+		if got := sniffMagic(bufio.NewReader(strings.NewReader(test.input))); !bytes.Equal(got, test.magic) {
+			t.Errorf("sniffMagic(%q) = %q, want %q", test.input, got, test.magic)
+		}
+	}
+}
+
+// TestSniffMagicDoesNotWaitForPlainInput sniffs a stream that has sent one
+// short line and stays open, as tail -f does.
+func TestSniffMagicDoesNotWaitForPlainInput(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	go writer.Write([]byte("5\n"))
+	done := make(chan []byte)
+	go func() { done <- sniffMagic(bufio.NewReader(reader)) }()
+	select {
+	case magic := <-done:
+		if magic != nil {
+			t.Errorf("sniffMagic = %q, want nil", magic)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sniffMagic waited for more than the first line")
+	}
+}
@@ -0,0 +1,5 @@
+module d00
+
+go 1.22
+
+require github.com/klauspost/compress v1.18.0
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"d00/stats"
+)
+
+// Policies for lines that are not a valid value.
+const (
+	onErrorFail   = "fail"
+	onErrorSkip   = "skip"
+	onErrorReport = "report"
+)
+
+var errorPolicies = map[string]bool{onErrorFail: true, onErrorSkip: true, onErrorReport: true}
+
+// lineError is a rejected input line. file is empty for stdin.
+type lineError struct {
+	file string
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string {
+	if e.file != "" {
+		return fmt.Sprintf("%s: line %d: %v", e.file, e.line, e.err)
+	}
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+type readSummary struct {
+	accepted int
+	rejected int
+}
+
+// print goes to stderr so that stdout only carries the metrics.
+func (summary readSummary) print() {
+	fmt.Fprintf(os.Stderr, "Lines accepted: %d, rejected: %d\n", summary.accepted, summary.rejected)
+}
+
+// reject applies the error policy to a bad line. It returns the error to
+// stop on, or nil to carry on with the next line.
+func (summary *readSummary) reject(cfg *config, lineErr *lineError) error {
+	switch cfg.onError {
+	case onErrorFail:
+		return lineErr
+	case onErrorReport:
+		fmt.Fprintln(os.Stderr, "Rejected", lineErr)
+	}
+	summary.rejected++
+	return nil
+}
+
+// column is one input column and everything computed over it.
+type column struct {
+	name string
+	acc  *stats.Accumulator
+}
+
+// columnPair follows two columns together for -corr.
+type columnPair struct {
+	x, y int
+	acc  *stats.PairAccumulator
+}
+
+// dataset is what read_data passes on: one column for plain input, one per
+// selected column for delimited input. file is set for the datasets of
+// single files -per-file adds.
+type dataset struct {
+	columns []column
+	pairs   []columnPair
+	file    string
+}
+
+// read_data reads every input in turn and hands every dataset in them to
+// emit: a single one with an empty key, or one per group for -group-by.
+// With -per-file each file's own dataset is emitted as soon as the file
+// ends, and the combined one last.
+func read_data(paths []string, cfg *config, emit func(key string, data *dataset) error) (readSummary, error) {
+	if cfg.table != nil {
+		return readTable(paths, cfg, emit)
+	}
+	var summary readSummary
+	combined, err := newPlainDataset(cfg)
+	if err != nil {
+		return summary, err
+	}
+	for _, path := range paths {
+		targets := []*stats.Accumulator{combined.columns[0].acc}
+		var perFile *dataset
+		if cfg.perFile {
+			if perFile, err = newPlainDataset(cfg); err != nil {
+				return summary, err
+			}
+			perFile.file = path
+			targets = append(targets, perFile.columns[0].acc)
+		}
+		if err := readPlain(path, cfg, &summary, targets); err != nil {
+			return summary, err
+		}
+		if perFile != nil {
+			if err := emit("", perFile); err != nil {
+				return summary, err
+			}
+		}
+	}
+	return summary, emit("", combined)
+}
+
+func newPlainDataset(cfg *config) (*dataset, error) {
+	acc, err := stats.New(cfg.options)
+	if err != nil {
+		return nil, err
+	}
+	return &dataset{columns: []column{{acc: acc}}}, nil
+}
+
+// tableConfig describes delimited input. weight and groupBy name a column
+// like the entries of columns do.
+type tableConfig struct {
+	delim   rune
+	header  bool
+	columns []string
+	corr    bool
+	weight  string
+	groupBy string
+	// sorted promises the input is sorted by the group key, so each group
+	// can be printed and dropped as soon as the next one starts.
+	sorted bool
+}
+
+func parseDelim(delim string) (rune, error) {
+	switch delim {
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	runes := []rune(delim)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character: %q", delim)
+	}
+	return runes[0], nil
+}
+
+// tableLayout is where the values, the weight and the group key are found
+// in a record. weight and group are -1 when not used.
+type tableLayout struct {
+	values []int
+	names  []string
+	weight int
+	group  int
+}
+
+// readTable reads delimited records. A record is accepted only if every
+// selected column holds a valid value, so all columns and pairs are
+// computed over the same rows.
+func readTable(paths []string, cfg *config, emit func(key string, data *dataset) error) (readSummary, error) {
+	var summary readSummary
+	table := &tableReader{cfg: cfg, emit: emit}
+	for _, path := range paths {
+		if err := table.read(path, &summary); err != nil {
+			return summary, err
+		}
+	}
+	if err := table.resolve(len(table.header)); err != nil {
+		return summary, err
+	}
+	return summary, table.groups.flush()
+}
+
+// tableReader holds what every input of readTable shares: the header and
+// layout found in the first one, and the groups all records go to.
+type tableReader struct {
+	cfg        *config
+	emit       func(key string, data *dataset) error
+	header     []string
+	headerFile string
+	layout     *tableLayout
+	groups     *groupSet
+	values     []stats.Number
+}
+
+// resolve sets up the layout for records of width fields, the first time
+// only. With a header, records are as wide as the header.
+func (table *tableReader) resolve(width int) error {
+	if table.layout != nil {
+		return nil
+	}
+	if table.header != nil {
+		width = len(table.header)
+	}
+	layout, err := newTableLayout(table.cfg.table, table.header, width)
+	if err != nil {
+		return err
+	}
+	table.layout = layout
+	table.groups = newGroupSet(table.cfg, layout, table.emit)
+	return nil
+}
+
+// read adds the records of one input. Every input must have the header of
+// the first one. With -per-file the records also go to groups of their own,
+// which are emitted when the input ends.
+func (table *tableReader) read(path string, summary *readSummary) error {
+	input, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	cfg := table.cfg
+	reader := csv.NewReader(input)
+	reader.Comma = cfg.table.delim
+	reader.FieldsPerRecord = -1
+	if cfg.table.header {
+		record, err := reader.Read()
+		if err != nil {
+			return inputError(path, fmt.Errorf("reading header: %w", err))
+		}
+		if table.header == nil {
+			table.header, table.headerFile = record, path
+		} else if !slices.Equal(record, table.header) {
+			return inputError(path, fmt.Errorf("header differs from the one of %s", table.headerFile))
+		}
+	}
+	var fileGroups *groupSet
+	newFileGroups := func() {
+		fileGroups = newGroupSet(cfg, table.layout, func(key string, data *dataset) error {
+			data.file = path
+			return table.emit(key, data)
+		})
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := summary.reject(cfg, &lineError{file: fileLabel(path), line: parseErr.Line, err: parseErr.Err}); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return inputError(path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if table.header != nil && len(record) < len(table.header) {
+			lineErr := &lineError{file: fileLabel(path), line: line,
+				err: fmt.Errorf("%d fields, the header has %d", len(record), len(table.header))}
+			if err := summary.reject(cfg, lineErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := table.resolve(len(record)); err != nil {
+			return err
+		}
+		if cfg.perFile && fileGroups == nil {
+			newFileGroups()
+		}
+		key, weight, err := table.layout.parseRow(record, table.groups.parser, &table.values)
+		if err != nil {
+			if err := summary.reject(cfg, &lineError{file: fileLabel(path), line: line, err: err}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, groups := range []*groupSet{fileGroups, table.groups} {
+			if groups == nil {
+				continue
+			}
+			data, err := groups.get(key)
+			if err != nil {
+				return &lineError{file: fileLabel(path), line: line, err: err}
+			}
+			for i, num := range table.values {
+				if err := data.columns[i].acc.AddWeighted(num, weight); err != nil {
+					return &lineError{file: fileLabel(path), line: line, err: err}
+				}
+			}
+			for _, pair := range data.pairs {
+				pair.acc.Add(table.values[pair.x].Float, table.values[pair.y].Float)
+			}
+		}
+		summary.accepted++
+	}
+	if !cfg.perFile {
+		return nil
+	}
+	if err := table.resolve(len(table.header)); err != nil {
+		return err
+	}
+	if fileGroups == nil {
+		newFileGroups()
+	}
+	return fileGroups.flush()
+}
+
+// parseRow reads the group key, the weight and the selected values of a
+// record into values.
+func (layout *tableLayout) parseRow(record []string, parser stats.Parser, values *[]stats.Number) (string, uint64, error) {
+	cell := func(index int, name string) (string, error) {
+		if index >= len(record) {
+			return "", fmt.Errorf("column %s: missing", name)
+		}
+		return strings.TrimSpace(record[index]), nil
+	}
+	var key string
+	if layout.group >= 0 {
+		var err error
+		if key, err = cell(layout.group, "group"); err != nil {
+			return "", 0, err
+		}
+	}
+	weight := uint64(1)
+	if layout.weight >= 0 {
+		text, err := cell(layout.weight, "weight")
+		if err != nil {
+			return "", 0, err
+		}
+		if weight, err = strconv.ParseUint(text, 10, 64); err != nil {
+			return "", 0, fmt.Errorf("column weight: %w", err)
+		}
+		if weight > stats.MaxWeight {
+			return "", 0, fmt.Errorf("column weight: %d is more than %d", weight, uint64(stats.MaxWeight))
+		}
+	}
+	*values = (*values)[:0]
+	for i, index := range layout.values {
+		text, err := cell(index, layout.names[i])
+		if err != nil {
+			return "", 0, err
+		}
+		num, err := parser.Parse(text)
+		if err != nil {
+			return "", 0, fmt.Errorf("column %s: %w", layout.names[i], err)
+		}
+		*values = append(*values, num)
+	}
+	return key, weight, nil
+}
+
+// newTableLayout resolves -cols, -weight and -group-by against the header,
+// by name or by 1-based position. Without -cols every column of the header,
+// or else of the first record, other than the weight and the group key is
+// a value column.
+func newTableLayout(table *tableConfig, header []string, width int) (*tableLayout, error) {
+	layout := &tableLayout{weight: -1, group: -1}
+	var err error
+	if table.weight != "" {
+		if layout.weight, err = columnIndex(header, table.weight); err != nil {
+			return nil, err
+		}
+	}
+	if table.groupBy != "" {
+		if layout.group, err = columnIndex(header, table.groupBy); err != nil {
+			return nil, err
+		}
+	}
+	if len(table.columns) == 0 {
+		for i := 0; i < width; i++ {
+			if i != layout.weight && i != layout.group {
+				layout.values = append(layout.values, i)
+			}
+		}
+	}
+	for _, name := range table.columns {
+		index, err := columnIndex(header, name)
+		if err != nil {
+			return nil, err
+		}
+		layout.values = append(layout.values, index)
+	}
+	for _, index := range layout.values {
+		name := "col" + strconv.Itoa(index+1)
+		if index < len(header) {
+			name = strings.TrimSpace(header[index])
+		}
+		layout.names = append(layout.names, name)
+	}
+	return layout, nil
+}
+
+func columnIndex(header []string, name string) (int, error) {
+	for i, title := range header {
+		if strings.TrimSpace(title) == name {
+			return i, nil
+		}
+	}
+	position, err := strconv.Atoi(name)
+	if err != nil || position < 1 {
+		return 0, fmt.Errorf("unknown column %q", name)
+	}
+	return position - 1, nil
+}
+
+func newDataset(cfg *config, names []string) (*dataset, error) {
+	data := &dataset{}
+	for _, name := range names {
+		acc, err := stats.New(cfg.options)
+		if err != nil {
+			return nil, err
+		}
+		data.columns = append(data.columns, column{name: name, acc: acc})
+	}
+	if cfg.table.corr {
+		for x := range data.columns {
+			for y := x + 1; y < len(data.columns); y++ {
+				data.pairs = append(data.pairs, columnPair{x: x, y: y, acc: stats.NewPairAccumulator()})
+			}
+		}
+	}
+	return data, nil
+}
+
+// groupSet holds one dataset per group key. Without -group-by every record
+// has the empty key.
+type groupSet struct {
+	cfg    *config
+	layout *tableLayout
+	emit   func(key string, data *dataset) error
+	byKey  map[string]*dataset
+	// parser reads values for every group; they all share cfg.options.
+	parser stats.Parser
+	// last is the key of the group being read with -sorted.
+	last string
+}
+
+func newGroupSet(cfg *config, layout *tableLayout, emit func(string, *dataset) error) *groupSet {
+	parser, _ := stats.NewParser(cfg.options)
+	return &groupSet{cfg: cfg, layout: layout, emit: emit, byKey: make(map[string]*dataset), parser: parser}
+}
+
+// get returns the dataset of a group, creating it on its first record.
+// With -sorted the previous group is printed and dropped first, and a key
+// going backwards is an error.
+func (groups *groupSet) get(key string) (*dataset, error) {
+	if data, ok := groups.byKey[key]; ok {
+		return data, nil
+	}
+	if groups.cfg.table.sorted && len(groups.byKey) != 0 {
+		if compareKeys(key, groups.last) < 0 {
+			return nil, fmt.Errorf("group %q after %q: input is not sorted by %s", key, groups.last, groups.cfg.table.groupBy)
+		}
+		if err := groups.emit(groups.last, groups.byKey[groups.last]); err != nil {
+			return nil, err
+		}
+		delete(groups.byKey, groups.last)
+	}
+	data, err := newDataset(groups.cfg, groups.layout.names)
+	if err != nil {
+		return nil, err
+	}
+	groups.byKey[key] = data
+	groups.last = key
+	return data, nil
+}
+
+// flush emits the groups still held, sorted by key. Ungrouped input always
+// has its one dataset, even an empty one.
+func (groups *groupSet) flush() error {
+	if groups.cfg.table.groupBy == "" {
+		data, err := groups.get("")
+		if err != nil {
+			return err
+		}
+		return groups.emit("", data)
+	}
+	if len(groups.byKey) == 0 {
+		return stats.ErrEmpty
+	}
+	keys := make([]string, 0, len(groups.byKey))
+	for key := range groups.byKey {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return compareKeys(keys[i], keys[j]) < 0
+	})
+	for _, key := range keys {
+		if err := groups.emit(key, groups.byKey[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareKeys orders group keys: numbers first, by value, then the other
+// keys as strings. Equal numbers spelled differently, like 1 and 1.0, are
+// ordered as strings too, so the order is total.
+func compareKeys(a, b string) int {
+	x, errX := strconv.ParseFloat(a, 64)
+	y, errY := strconv.ParseFloat(b, 64)
+	numX, numY := errX == nil && !math.IsNaN(x), errY == nil && !math.IsNaN(y)
+	switch {
+	case numX != numY:
+		if numX {
+			return -1
+		}
+		return 1
+	case numX && x < y:
+		return -1
+	case numX && x > y:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
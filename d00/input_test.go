@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"d00/stats"
+)
+
+// FuzzReadData checks that every line or record is either accepted or
+// rejected, and that every accepted one reaches every column.
+func FuzzReadData(f *testing.F) {
+	for _, seed := range []string{
+		"1\n2\n3\n", "", "\n", "x\n", "-100000\n100000\n100001\n", "1\r\n2", " 3\n", "1,2\n3\n4,5,6\n", "\"1\",2\n\"3\n",
+	} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, input string, table bool) {
+		path := filepath.Join(t.TempDir(), "input")
+		if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &config{options: stats.DefaultOptions(), format: "text", onError: onErrorSkip, workers: 2}
+		if table {
+			cfg.table = &tableConfig{delim: ','}
+		}
+		var columns []column
+		summary, err := read_data([]string{path}, cfg, func(_ string, data *dataset) error {
+			columns = data.columns
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		for _, col := range columns {
+			if col.acc.Count() != int64(summary.accepted) {
+				t.Errorf("column %q holds %d values, %d lines were accepted", col.name, col.acc.Count(), summary.accepted)
+			}
+		}
+		if table {
+			return
+		}
+		lines := 0
+		scanner := bufio.NewScanner(strings.NewReader(input))
+		for scanner.Scan() {
+			lines++
+		}
+		if summary.accepted+summary.rejected != lines {
+			t.Errorf("%d lines accepted and %d rejected out of %d", summary.accepted, summary.rejected, lines)
+		}
+	})
+}
+
+func TestCompareKeysIsATotalOrder(t *testing.T) {
+	keys := []string{"2", "10", "1a", "1", "1.0", "-3", "NaN", "Inf", "", "b", "a"}
+	for _, a := range keys {
+		if compareKeys(a, a) != 0 {
+			t.Errorf("compareKeys(%q, %q) != 0", a, a)
+		}
+		for _, b := range keys {
+			if compareKeys(a, b) != -compareKeys(b, a) {
+				t.Errorf("compareKeys(%q, %q) and (%q, %q) disagree", a, b, b, a)
+			}
+			for _, c := range keys {
+				if compareKeys(a, b) < 0 && compareKeys(b, c) < 0 && compareKeys(a, c) >= 0 {
+					t.Errorf("%q < %q < %q but not %q < %q", a, b, c, a, c)
+				}
+			}
+		}
+	}
+	sorted := []string{"-3", "1", "1.0", "2", "10", "Inf", "", "1a", "NaN", "a", "b"}
+	for i := 1; i < len(sorted); i++ {
+		if compareKeys(sorted[i-1], sorted[i]) >= 0 {
+			t.Errorf("%q is not before %q", sorted[i-1], sorted[i])
+		}
+	}
+}
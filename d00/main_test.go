@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"d00/stats"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the current output")
+
+// runMainEnv makes the test binary run d00 itself, so the golden tests
+// exercise the real flag parsing, output and exit codes.
+const runMainEnv = "D00_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// goldenTests run d00 with args on stdin. The golden file holds stdout,
+// stderr and the exit code.
+var goldenTests = []struct {
+	name  string
+	args  []string
+	stdin string
+}{
+	{"default", nil, "1\n5\n3\n3\n10\n"},
+	{"even_median", []string{"-median"}, "4\n1\n3\n2\n"},
+	{"single_value", nil, "42\n"},
+	{"negative_values", nil, "-5\n-1\n-1\n-3\n"},
+	{"tied_modes", []string{"-mode", "-modes", "-top", "2"}, "3\n3\n1\n1\n2\n"},
+	{"no_repeats", []string{"-modes"}, "1\n2\n3\n"},
+	{"empty", nil, ""},
+	{"fail_on_bad_line", nil, "1\nx\n3\n"},
+	{"skip_bad_lines", []string{"-on-error", "skip"}, "1\nx\n3\n"},
+	{"report_bad_lines", []string{"-on-error", "report"}, "1\nx\n3\n100001\n"},
+	{"usage_error", []string{"-top", "-1"}, ""},
+	{"extended", []string{"-minimum", "-maximum", "-range", "-iqr", "-var", "-svar", "-skew", "-kurt", "-cv", "-mad", "-p", "10,90"},
+		"2\n4\n4\n4\n5\n5\n7\n9\n"},
+	{"json", []string{"-format", "json", "-modes"}, "1\n2\n2\n4\n"},
+	{"csv", []string{"-format", "csv", "-top", "2"}, "1\n2\n2\n4\n"},
+	{"yaml", []string{"-format", "yaml", "-modes"}, "1\n2\n2\n4\n"},
+	{"float", []string{"-type", "float"}, "0.1\n0.25\n1e2\n"},
+	{"decimal", []string{"-type", "decimal", "-var"}, "0.1\n0.2\n0.35\n"},
+	{"float_range", []string{"-type", "float", "-range", "-minimum"}, "0.1\n0.3\n"},
+	{"precision", []string{"-precision", "4", "-rounding", "truncate", "-format", "json"}, "1\n2\n2\n4\n"},
+	{"half_even", []string{"-type", "decimal", "-rounding", "half-even", "-mean"}, "0.125\n0.125\n"},
+	{"exact", []string{"-exact", "-mean", "-median"}, "1\n2\n2\n5\n"},
+	{"mean_rounds_as_decimal", []string{"-mean", "-format", "json"}, strings.Repeat("-3\n", 39) + "0\n"},
+	{"mean_rounds_as_decimal_text", []string{"-mean", "-median"}, strings.Repeat("3\n", 39) + "0\n"},
+	{"negative_zero", []string{"-mean"}, "-1\n" + strings.Repeat("0\n", 999)},
+	{"histogram", []string{"-hist", "-bins", "3"}, "1\n2\n2\n3\n3\n3\n9\n"},
+	{"too_many_bins", []string{"-hist", "-bins", "100000000"}, "1\n2\n"},
+	{"histogram_extremes", []string{"-type", "float", "-min", "none", "-max", "none", "-hist", "-bins", "4"}, "1e308\n-1e308\n0\n"},
+	{"confidence", []string{"-ci", "95", "-resamples", "200", "-seed", "7"}, "1\n2\n2\n3\n4\n5\n8\n"},
+	{"window", []string{"-window", "3", "-every", "2", "-mean", "-median"}, "1\n2\n3\n4\n5\n"},
+	{"outliers", []string{"-outliers", "iqr"}, "1\n2\n3\n2\n100\n3\n"},
+	{"outliers_clean", []string{"-outliers", "zscore", "-k", "1.5", "-clean", "-format", "json"}, "1\n2\n3\n2\n100\n3\n"},
+	{"table", []string{"-header", "-cols", "price,qty", "-corr", "-mean", "-sd"}, "price,qty\n1,2\n2,4\n3,5\n"},
+	{"weight_too_large", []string{"-header", "-cols", "v", "-weight", "w", "-mean", "-on-error", "report"}, "v,w\n5,1\n7,99999999999999999\n"},
+	{"short_row", []string{"-header", "-corr"}, "a,b\n1\n2,3\n"},
+	{"group_by", []string{"-group-by", "region", "-type", "float", "-header", "-cols", "price", "-weight", "qty", "-mean", "-format", "json",
+		"testdata/input/sales.csv"}, ""},
+	{"compare", []string{"-a", "testdata/input/a.txt", "-b", "testdata/input/b.txt"}, ""},
+	{"compare_precision", []string{"-a", "testdata/input/a.txt", "-b", "testdata/input/b.txt", "-precision", "5", "-rounding", "truncate", "-format", "json"}, ""},
+	{"per_file", []string{"-per-file", "-mean", "testdata/input/a.txt", "testdata/input/*.gz"}, ""},
+}
+
+func TestGolden(t *testing.T) {
+	for _, test := range goldenTests {
+		t.Run(test.name, func(t *testing.T) {
+			got := runD00(t, test.stdin, test.args...)
+			path := filepath.Join("testdata", "golden", test.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("d00 %s\ngot:\n%s\nwant:\n%s", strings.Join(test.args, " "), got, want)
+			}
+		})
+	}
+}
+
+// TestWorkersDoNotChangeResults reads an input spanning several chunks
+// with different numbers of workers, and checks every run against a single
+// pass over the same values.
+func TestWorkersDoNotChangeResults(t *testing.T) {
+	acc := stats.NewAccumulator()
+	var input strings.Builder
+	for i := 0; input.Len() < 2*chunkSize+chunkSize/2; i++ {
+		value := (i*7919)%200001 - 100000
+		if err := acc.AddInt(int64(value)); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(&input, value)
+	}
+	path := filepath.Join(t.TempDir(), "large.txt")
+	if err := os.WriteFile(path, []byte(input.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"mean", "sd", "skew", "kurt", "median"}
+	metrics, err := stats.Lookup(names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := acc.Compute(metrics...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(format numberFormat) { outputFormat = format }(outputFormat)
+	outputFormat.precision = 12
+	want := make(map[string]string)
+	for _, result := range results {
+		want[result.Name] = resultScalar(result).text
+	}
+	args := []string{"-format", "json", "-precision", "12", path}
+	for _, name := range names {
+		args = append([]string{"-" + name}, args...)
+	}
+	for _, workers := range []string{"1", "2", "5"} {
+		out := runD00(t, "", append([]string{"-workers", workers}, args...)...)
+		var got map[string]json.Number
+		decoder := json.NewDecoder(bytes.NewReader(out))
+		decoder.UseNumber()
+		if err := decoder.Decode(&got); err != nil {
+			t.Fatalf("-workers %s: %v in:\n%s", workers, err, out)
+		}
+		for name, value := range want {
+			if string(got[name]) != value {
+				t.Errorf("-workers %s: %s is %s, a single pass gives %s", workers, name, got[name], value)
+			}
+		}
+	}
+}
+
+// runD00 runs d00 and returns its stdout, stderr and exit code.
+func runD00(t *testing.T, stdin string, args ...string) []byte {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatal(err)
+		}
+		code = exitErr.ExitCode()
+	}
+	fmt.Fprintf(&stdout, "--- stderr\n%s--- exit %d\n", stderr.Bytes(), code)
+	return stdout.Bytes()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"d00/stats"
+)
+
+// outlierConfig is set by -outliers.
+type outlierConfig struct {
+	method    string
+	threshold float64
+	// clean writes the lines that are not outliers to stdout, and the
+	// report to stderr instead.
+	clean bool
+}
+
+// inputLine is an accepted line kept for the second look -outliers needs.
+type inputLine struct {
+	line int
+	text string
+	num  stats.Number
+}
+
+// runOutliers reads the whole input, builds the fence of the -outliers rule
+// over it and reports the values outside of it with their line numbers.
+// Unlike the other modes it keeps every accepted line in memory, since the
+// fence is only known once the input has ended.
+func runOutliers(input io.Reader, cfg *config) (readSummary, error) {
+	var summary readSummary
+	acc, err := stats.New(cfg.options)
+	if err != nil {
+		return summary, err
+	}
+	var lines []inputLine
+	scanner := bufio.NewScanner(input)
+	for line := 1; scanner.Scan(); line++ {
+		num, err := acc.Parse(scanner.Text())
+		if err == nil {
+			err = acc.AddNumber(num)
+		}
+		if err != nil {
+			if err := summary.reject(cfg, &lineError{line: line, err: err}); err != nil {
+				return summary, err
+			}
+			continue
+		}
+		summary.accepted++
+		lines = append(lines, inputLine{line: line, text: scanner.Text(), num: num})
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+	fence, err := stats.NewFence(acc, cfg.outliers.method, cfg.outliers.threshold)
+	if err != nil {
+		return summary, err
+	}
+	var outliers []inputLine
+	clean := bufio.NewWriter(os.Stdout)
+	for _, line := range lines {
+		if !fence.Contains(line.num) {
+			outliers = append(outliers, line)
+		} else if cfg.outliers.clean {
+			fmt.Fprintln(clean, line.text)
+		}
+	}
+	if err := clean.Flush(); err != nil {
+		return summary, err
+	}
+	out := os.Stdout
+	if cfg.outliers.clean {
+		out = os.Stderr
+	}
+	printOutliers(out, cfg.format, fence, outliers)
+	return summary, nil
+}
+
+func printOutliers(out io.Writer, format string, fence stats.Fence, outliers []inputLine) {
+	if format == "text" {
+		fmt.Fprintf(out, "Outliers (%s, k = %s, accepted [%s, %s]): %d\n", fence.Method,
+			textFloat(fence.Threshold), textFloat(fence.Low), textFloat(fence.High), len(outliers))
+		for _, outlier := range outliers {
+			fmt.Fprintf(out, "line %d: %s\n", outlier.line, outlier.num)
+		}
+		return
+	}
+	values := make(list, 0, len(outliers))
+	for _, outlier := range outliers {
+		values = append(values, object{{"line", intScalar(outlier.line)}, {"value", numberScalar(outlier.num)}})
+	}
+	formats[format](out, object{
+		{"method", stringScalar(fence.Method)},
+		{"threshold", floatScalar(fence.Threshold)},
+		{"low", floatScalar(fence.Low)},
+		{"high", floatScalar(fence.High)},
+		{"count", intScalar(len(outliers))},
+		{"outliers", values},
+	})
+}
@@ -0,0 +1,520 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"d00/stats"
+)
+
+// The machine-readable formats all render the same small document model:
+// objects keep their fields in order, lists hold objects or scalars, and
+// scalars are pre-rendered text tagged with what they are.
+type (
+	field struct {
+		key   string
+		value any
+	}
+	object []field
+	list   []any
+	scalar struct {
+		text string
+		kind scalarKind
+	}
+	scalarKind int
+)
+
+const (
+	scalarNumber scalarKind = iota
+	scalarString
+	scalarBool
+	scalarNaN
+	scalarPosInf
+	scalarNegInf
+)
+
+func stringScalar(s string) scalar {
+	return scalar{text: s, kind: scalarString}
+}
+
+func intScalar[T int | int64 | uint64](i T) scalar {
+	return scalar{text: fmt.Sprint(i), kind: scalarNumber}
+}
+
+func boolScalar(b bool) scalar {
+	return scalar{text: strconv.FormatBool(b), kind: scalarBool}
+}
+
+// floatScalar rounds by outputFormat. Every machine-readable format keeps
+// all of its decimals, so consumers never see "3.5" in one run and "3.50"
+// in another.
+func floatScalar(f float64) scalar {
+	switch {
+	case math.IsNaN(f):
+		return scalar{kind: scalarNaN}
+	case math.IsInf(f, 1):
+		return scalar{kind: scalarPosInf}
+	case math.IsInf(f, -1):
+		return scalar{kind: scalarNegInf}
+	}
+	return scalar{text: outputFormat.round(floatRat(f)), kind: scalarNumber}
+}
+
+// floatRat is the shortest decimal that reads back as f. Rounding that
+// instead of the binary expansion of f rounds a mean of -2.925 to -2.93,
+// as the decimal it stands for, not to -2.92 because the float closest to
+// it is a hair above.
+func floatRat(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return r
+}
+
+// numberScalar prints an input value as it was read.
+func numberScalar(num stats.Number) scalar {
+	if num.Exact == nil && (math.IsNaN(num.Float) || math.IsInf(num.Float, 0)) {
+		return floatScalar(num.Float)
+	}
+	return scalar{text: num.String(), kind: scalarNumber}
+}
+
+func resultScalar(result stats.Result) scalar {
+	if result.Discrete {
+		return numberScalar(resultNumber(result))
+	}
+	if exactResult(result) {
+		return stringScalar(result.Exact.RatString())
+	}
+	if result.Exact != nil {
+		return scalar{text: outputFormat.round(result.Exact), kind: scalarNumber}
+	}
+	return floatScalar(result.Value)
+}
+
+// report is everything d00 prints for one dataset. modes, top, intervals
+// and hist are only filled in when -modes, -top, -ci and -hist ask for them.
+type report struct {
+	results   []stats.Result
+	modes     *stats.ModeReport
+	top       []stats.Frequency
+	intervals []stats.Interval
+	hist      []stats.Bin
+}
+
+func (rep report) object() object {
+	doc := make(object, 0, len(rep.results)+4)
+	for _, result := range rep.results {
+		doc = append(doc, field{result.Name, resultScalar(result)})
+	}
+	if rep.modes != nil {
+		values := make(list, 0, len(rep.modes.Modes))
+		for _, mode := range rep.modes.Modes {
+			values = append(values, numberScalar(mode))
+		}
+		doc = append(doc, field{"modes", object{
+			{"values", values},
+			{"count", intScalar(rep.modes.Count)},
+			{"no_repeats", boolScalar(rep.modes.NoRepeats())},
+		}})
+	}
+	if rep.top != nil {
+		top := make(list, 0, len(rep.top))
+		for _, freq := range rep.top {
+			top = append(top, object{{"value", numberScalar(freq.Value)}, {"count", intScalar(freq.Count)}})
+		}
+		doc = append(doc, field{"top", top})
+	}
+	if rep.intervals != nil {
+		intervals := make(list, 0, len(rep.intervals))
+		for _, ci := range rep.intervals {
+			intervals = append(intervals, object{
+				{"metric", stringScalar(ci.Name)},
+				{"method", stringScalar(ci.Method)},
+				{"level", floatScalar(ci.Level)},
+				{"low", floatScalar(ci.Low)},
+				{"high", floatScalar(ci.High)},
+			})
+		}
+		doc = append(doc, field{"ci", intervals})
+	}
+	if rep.hist != nil {
+		bins := make(list, 0, len(rep.hist))
+		for _, bin := range rep.hist {
+			bins = append(bins, object{
+				{"low", floatScalar(bin.Low)},
+				{"high", floatScalar(bin.High)},
+				{"count", intScalar(bin.Count)},
+			})
+		}
+		doc = append(doc, field{"histogram", bins})
+	}
+	return doc
+}
+
+// formats print a document in the machine-readable output formats.
+var formats = map[string]func(w io.Writer, doc object){
+	"json": printJSON,
+	"csv":  printCSV,
+	"yaml": printYAML,
+}
+
+func validFormat(format string) bool {
+	_, ok := formats[format]
+	return ok || format == "text"
+}
+
+func resultNumber(result stats.Result) stats.Number {
+	return stats.Number{Float: result.Value, Exact: result.Exact}
+}
+
+// exactResult reports whether -exact prints result as a fraction.
+func exactResult(result stats.Result) bool {
+	return outputFormat.exact && exactMetrics[result.Name] && result.Exact != nil
+}
+
+// textExact applies the text format's rule to an exact value: integers get
+// a ".0", anything else is rounded by outputFormat without trailing zeros.
+func textExact(exact *big.Rat) string {
+	if exact.IsInt() {
+		return exact.Num().String() + ".0"
+	}
+	rounded := outputFormat.round(exact)
+	if strings.Contains(rounded, ".") {
+		rounded = strings.TrimSuffix(strings.TrimRight(rounded, "0"), ".")
+	}
+	return rounded
+}
+
+// textFloat is textExact for a float, read as its shortest decimal. NaN
+// and infinities print as Go prints them.
+func textFloat(value float64) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Sprint(value)
+	}
+	return textExact(floatRat(value))
+}
+
+// textResult prints input values as they were read, the mean and median
+// as fractions for -exact, and applies the text format's rule to anything
+// else.
+func textResult(result stats.Result) string {
+	if result.Discrete {
+		return resultNumber(result).String()
+	}
+	if exactResult(result) {
+		return result.Exact.RatString()
+	}
+	if result.Exact != nil {
+		return textExact(result.Exact)
+	}
+	return textFloat(result.Value)
+}
+
+func printTextValue(label string, value float64) {
+	fmt.Println(label+":", textFloat(value))
+}
+
+func (rep report) printText() {
+	for _, result := range rep.results {
+		fmt.Println(result.Label+":", textResult(result))
+	}
+	if rep.modes != nil {
+		if rep.modes.NoRepeats() {
+			fmt.Println("Modes: none, every value occurs once")
+		} else {
+			fmt.Printf("Modes: %s (count %d)\n", joinValues(rep.modes.Modes, " "), rep.modes.Count)
+		}
+	}
+	if rep.top != nil {
+		fmt.Printf("Top %d:\n", len(rep.top))
+		for _, freq := range rep.top {
+			fmt.Printf("%s\t%d\n", freq.Value, freq.Count)
+		}
+	}
+	for _, ci := range rep.intervals {
+		fmt.Printf("%s %s%% CI: [%s, %s] (%s)\n", ci.Label, strconv.FormatFloat(ci.Level*100, 'g', 10, 64),
+			textFloat(ci.Low), textFloat(ci.High), ci.Method)
+	}
+	if rep.hist != nil {
+		printHistogram(rep.hist)
+	}
+}
+
+// histogramWidth is the length of the longest bar in the text histogram.
+const histogramWidth = 40
+
+// printHistogram draws one bar per bin, scaled to the fullest bin, and
+// then lists the bin edges and counts as a tab-separated table.
+func printHistogram(hist []stats.Bin) {
+	var fullest uint64
+	labels := make([]string, len(hist))
+	labelWidth := 0
+	for i, bin := range hist {
+		if bin.Count > fullest {
+			fullest = bin.Count
+		}
+		closing := ")"
+		if i == len(hist)-1 {
+			closing = "]"
+		}
+		labels[i] = "[" + textFloat(bin.Low) + ", " + textFloat(bin.High) + closing
+		if len(labels[i]) > labelWidth {
+			labelWidth = len(labels[i])
+		}
+	}
+	fmt.Printf("Histogram (%d bins):\n", len(hist))
+	for i, bin := range hist {
+		bar := int(bin.Count * histogramWidth / fullest)
+		if bar == 0 && bin.Count != 0 {
+			bar = 1
+		}
+		fmt.Printf("%-*s  %-*s  %d\n", labelWidth, labels[i], histogramWidth, strings.Repeat("#", bar), bin.Count)
+	}
+	fmt.Println("Low\tHigh\tCount")
+	for _, bin := range hist {
+		fmt.Printf("%s\t%s\t%d\n", textFloat(bin.Low), textFloat(bin.High), bin.Count)
+	}
+}
+
+func joinValues(values []stats.Number, sep string) string {
+	fields := make([]string, 0, len(values))
+	for _, value := range values {
+		fields = append(fields, value.String())
+	}
+	return strings.Join(fields, sep)
+}
+
+func printJSON(w io.Writer, doc object) {
+	var b strings.Builder
+	writeJSON(&b, doc)
+	fmt.Fprintln(w, b.String())
+}
+
+func writeJSON(b *strings.Builder, value any) {
+	switch value := value.(type) {
+	case object:
+		b.WriteString("{")
+		for i, f := range value {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			key, _ := json.Marshal(f.key)
+			b.Write(key)
+			b.WriteString(": ")
+			writeJSON(b, f.value)
+		}
+		b.WriteString("}")
+	case list:
+		b.WriteString("[")
+		for i, item := range value {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeJSON(b, item)
+		}
+		b.WriteString("]")
+	case scalar:
+		switch value.kind {
+		case scalarString:
+			text, _ := json.Marshal(value.text)
+			b.Write(text)
+		case scalarNaN, scalarPosInf, scalarNegInf:
+			b.WriteString("null")
+		default:
+			b.WriteString(value.text)
+		}
+	}
+}
+
+var yamlPlainKey = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func yamlScalar(value scalar) string {
+	switch value.kind {
+	case scalarString:
+		return strconv.Quote(value.text)
+	case scalarNaN:
+		return ".nan"
+	case scalarPosInf:
+		return ".inf"
+	case scalarNegInf:
+		return "-.inf"
+	}
+	return value.text
+}
+
+func yamlKey(key string) string {
+	if yamlPlainKey.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func printYAML(w io.Writer, doc object) {
+	var b strings.Builder
+	writeYAMLObject(&b, doc, "", "")
+	fmt.Fprint(w, b.String())
+}
+
+// writeYAMLObject writes block-style fields. The first line is prefixed
+// with first (a list dash, for objects inside lists) and every other line
+// with indent.
+func writeYAMLObject(b *strings.Builder, doc object, first, indent string) {
+	for i, f := range doc {
+		prefix := indent
+		if i == 0 {
+			prefix = first
+		}
+		b.WriteString(prefix + yamlKey(f.key) + ":")
+		switch value := f.value.(type) {
+		case object:
+			b.WriteString("\n")
+			writeYAMLObject(b, value, indent+"  ", indent+"  ")
+		case list:
+			if !hasObjects(value) {
+				items := make([]string, 0, len(value))
+				for _, item := range value {
+					items = append(items, yamlScalar(item.(scalar)))
+				}
+				b.WriteString(" [" + strings.Join(items, ", ") + "]\n")
+				continue
+			}
+			b.WriteString("\n")
+			for _, item := range value {
+				writeYAMLObject(b, item.(object), indent+"  - ", indent+"    ")
+			}
+		case scalar:
+			b.WriteString(" " + yamlScalar(value) + "\n")
+		}
+	}
+}
+
+func hasObjects(items list) bool {
+	for _, item := range items {
+		if _, ok := item.(object); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// csvTable is a header plus rows; cells missing from a row stay empty.
+type csvTable struct {
+	header []string
+	index  map[string]int
+	rows   [][]string
+}
+
+func (table *csvTable) addRow(cells object) {
+	row := make([]string, len(table.header), len(table.header)+len(cells))
+	for _, cell := range cells {
+		i, ok := table.index[cell.key]
+		if !ok {
+			i = len(table.header)
+			table.index[cell.key] = i
+			table.header = append(table.header, cell.key)
+			row = append(row, "")
+		}
+		for len(row) <= i {
+			row = append(row, "")
+		}
+		row[i] = cell.value.(scalar).text
+	}
+	table.rows = append(table.rows, row)
+}
+
+// printCSV flattens the document into tables. Scalars at the top make up
+// one row, nested objects become dotted column names, lists of scalars are
+// joined with ';', and each top-level list of objects gets its own table
+// after a blank line. Objects nested deeper in a list are packed as
+// value:count style cells.
+func printCSV(w io.Writer, doc object) {
+	main := &csvTable{index: make(map[string]int)}
+	var tables []*csvTable
+	var mainRow object
+	for _, f := range doc {
+		if items, ok := f.value.(list); ok && hasObjects(items) {
+			table := &csvTable{index: make(map[string]int)}
+			for _, item := range items {
+				table.addRow(flattenCSV("", item.(object)))
+			}
+			tables = append(tables, table)
+			continue
+		}
+		mainRow = append(mainRow, flattenCSV("", object{f})...)
+	}
+	if len(mainRow) != 0 {
+		main.addRow(mainRow)
+		tables = append([]*csvTable{main}, tables...)
+	}
+	writer := csv.NewWriter(w)
+	for i, table := range tables {
+		if i > 0 {
+			writer.Flush()
+			fmt.Fprintln(w)
+		}
+		writer.Write(table.header)
+		for _, row := range table.rows {
+			for len(row) < len(table.header) {
+				row = append(row, "")
+			}
+			writer.Write(row)
+		}
+	}
+	writer.Flush()
+}
+
+func flattenCSV(prefix string, doc object) object {
+	var cells object
+	for _, f := range doc {
+		key := prefix + f.key
+		switch value := f.value.(type) {
+		case object:
+			cells = append(cells, flattenCSV(key+".", value)...)
+		case list:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, packCSV(item))
+			}
+			cells = append(cells, field{key, stringScalar(strings.Join(items, ";"))})
+		case scalar:
+			cells = append(cells, field{key, csvScalar(value)})
+		}
+	}
+	return cells
+}
+
+func packCSV(item any) string {
+	if doc, ok := item.(object); ok {
+		parts := make([]string, 0, len(doc))
+		for _, f := range doc {
+			parts = append(parts, packCSV(f.value))
+		}
+		return strings.Join(parts, ":")
+	}
+	if items, ok := item.(list); ok {
+		parts := make([]string, 0, len(items))
+		for _, part := range items {
+			parts = append(parts, packCSV(part))
+		}
+		return strings.Join(parts, ";")
+	}
+	return csvScalar(item.(scalar)).text
+}
+
+func csvScalar(value scalar) scalar {
+	switch value.kind {
+	case scalarNaN:
+		value.text = "NaN"
+	case scalarPosInf:
+		value.text = "+Inf"
+	case scalarNegInf:
+		value.text = "-Inf"
+	}
+	return value
+}
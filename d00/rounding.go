@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Rounding modes of -rounding. half-up rounds ties away from zero and
+// truncate rounds towards zero.
+const (
+	roundHalfEven = "half-even"
+	roundHalfUp   = "half-up"
+	roundTruncate = "truncate"
+)
+
+var roundingModes = map[string]bool{roundHalfEven: true, roundHalfUp: true, roundTruncate: true}
+
+// maxPrecision bounds -precision well past what a float64 carries.
+const maxPrecision = 20
+
+// numberFormat is how every computed value is printed, whatever the output
+// format: rounded to precision decimals with the rounding mode. With exact
+// the mean and median are printed as fractions instead.
+type numberFormat struct {
+	precision int
+	rounding  string
+	exact     bool
+}
+
+// outputFormat is set once from -precision, -rounding and -exact.
+var outputFormat = numberFormat{precision: 2, rounding: roundHalfUp}
+
+// exactMetrics are the metrics -exact prints as fractions.
+var exactMetrics = map[string]bool{"mean": true, "median": true}
+
+// round returns x with exactly nf.precision decimals. A negative x keeps
+// its sign even when it rounds to zero, as strconv and the text output
+// always have.
+func (nf numberFormat) round(x *big.Rat) string {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(nf.precision)), nil)
+	num := new(big.Int).Mul(x.Num(), scale)
+	quo, rem := new(big.Int).QuoRem(num, x.Denom(), new(big.Int))
+	if rem.Sign() != 0 && nf.rounding != roundTruncate {
+		twice := new(big.Int).Abs(rem)
+		twice.Lsh(twice, 1)
+		cmp := twice.Cmp(x.Denom())
+		if cmp > 0 || (cmp == 0 && (nf.rounding == roundHalfUp || quo.Bit(0) == 1)) {
+			quo.Add(quo, big.NewInt(int64(x.Sign())))
+		}
+	}
+	sign := ""
+	if x.Sign() < 0 {
+		sign = "-"
+	}
+	digits := new(big.Int).Abs(quo).String()
+	if nf.precision == 0 {
+		return sign + digits
+	}
+	if len(digits) <= nf.precision {
+		digits = strings.Repeat("0", nf.precision-len(digits)+1) + digits
+	}
+	point := len(digits) - nf.precision
+	return sign + digits[:point] + "." + digits[point:]
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value   string
+		format  numberFormat
+		rounded string
+	}{
+		{"0.125", numberFormat{precision: 2, rounding: roundHalfUp}, "0.13"},
+		{"0.125", numberFormat{precision: 2, rounding: roundHalfEven}, "0.12"},
+		{"0.135", numberFormat{precision: 2, rounding: roundHalfEven}, "0.14"},
+		{"0.129", numberFormat{precision: 2, rounding: roundTruncate}, "0.12"},
+		{"-0.125", numberFormat{precision: 2, rounding: roundHalfUp}, "-0.13"},
+		{"-0.129", numberFormat{precision: 2, rounding: roundTruncate}, "-0.12"},
+		{"-0.001", numberFormat{precision: 2, rounding: roundHalfUp}, "-0.00"},
+		{"2.5", numberFormat{precision: 0, rounding: roundHalfEven}, "2"},
+		{"2.5", numberFormat{precision: 0, rounding: roundHalfUp}, "3"},
+		{"1/3", numberFormat{precision: 4, rounding: roundHalfUp}, "0.3333"},
+		{"12", numberFormat{precision: 1, rounding: roundHalfUp}, "12.0"},
+	}
+	for _, test := range tests {
+		value, _ := new(big.Rat).SetString(test.value)
+		if got := test.format.round(value); got != test.rounded {
+			t.Errorf("%+v.round(%s) = %s, want %s", test.format, test.value, got, test.rounded)
+		}
+	}
+}
+
+// TestTextFloat covers the text format's rule: integers get a ".0", and
+// anything else is rounded without trailing zeros.
+func TestTextFloat(t *testing.T) {
+	tests := []struct {
+		value float64
+		text  string
+	}{
+		{3, "3.0"},
+		{-2, "-2.0"},
+		{0, "0.0"},
+		{2.5, "2.5"},
+		{1.0 / 3, "0.33"},
+		{2.999, "3"},
+		{-0.001, "-0"},
+		{2.925, "2.93"},
+		{-2.925, "-2.93"},
+		{1e21, "1000000000000000000000.0"},
+		{math.NaN(), "NaN"},
+		{math.Inf(1), "+Inf"},
+	}
+	for _, test := range tests {
+		if got := textFloat(test.value); got != test.text {
+			t.Errorf("textFloat(%v) = %q, want %q", test.value, got, test.text)
+		}
+	}
+}
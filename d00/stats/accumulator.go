@@ -0,0 +1,475 @@
+// Package stats computes descriptive statistics in a single pass over the
+// data. Integers in a bounded range are counted in a fixed-size histogram,
+// so memory stays constant however many values are read; floats and
+// decimals keep one counter per distinct value.
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+const (
+	MinValue = -100000
+	MaxValue = 100000
+
+	// maxExactInt is the largest integer a float64 holds exactly. Int data
+	// beyond it has to be read as decimal.
+	maxExactInt = 1 << 53
+	// MaxWeight is the largest weight AddWeighted takes, so that weighted
+	// counts stay exact.
+	MaxWeight = maxExactInt
+	// denseLimit is the widest integer range counted in a flat histogram;
+	// wider or unbounded ranges fall back to a map of distinct values.
+	denseLimit = 1 << 22
+)
+
+var (
+	ErrEmpty      = errors.New("no data")
+	ErrNotInteger = errors.New("value is not an integer")
+	ErrNotFinite  = errors.New("value is not a finite number")
+	ErrTooLarge   = errors.New("integer is too large, read it as decimal")
+)
+
+// Kind is how input values are parsed and stored.
+type Kind int
+
+const (
+	Int Kind = iota
+	Float
+	Decimal
+)
+
+var kindNames = map[string]Kind{"int": Int, "float": Float, "decimal": Decimal}
+
+func ParseKind(name string) (Kind, error) {
+	kind, ok := kindNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown input type %q", name)
+	}
+	return kind, nil
+}
+
+// Options configure an Accumulator. A nil bound disables that side of the
+// range check.
+type Options struct {
+	Kind Kind
+	Min  *big.Rat
+	Max  *big.Rat
+}
+
+// DefaultOptions accept integers in [MinValue:MaxValue].
+func DefaultOptions() Options {
+	return Options{
+		Kind: Int,
+		Min:  big.NewRat(MinValue, 1),
+		Max:  big.NewRat(MaxValue, 1),
+	}
+}
+
+// RangeError is returned for values outside the configured range.
+type RangeError struct {
+	Min *big.Rat
+	Max *big.Rat
+}
+
+func (e *RangeError) Error() string {
+	low, high := "-inf", "+inf"
+	if e.Min != nil {
+		low = e.Min.RatString()
+	}
+	if e.Max != nil {
+		high = e.Max.RatString()
+	}
+	return fmt.Sprintf("Value must be in range [%s:%s]", low, high)
+}
+
+// Accumulator collects everything the metrics need in a single pass over
+// the input. The central moments are kept with Welford's method, and for
+// decimal data also as exact power sums. Every value is counted, which is
+// enough to recover the exact median and mode without sorting the input,
+// and for int data the exact power sums as well: the moments of int data
+// are derived from those, so they do not depend on the order values were
+// added or merged in.
+type Accumulator struct {
+	parser
+	count int64
+	mean  float64
+	m2    float64
+	m3    float64
+	m4    float64
+	// sums holds the exact sums of x, x², x³ and x⁴ for decimal data.
+	sums   []*big.Rat
+	counts counter
+	// intSums caches the power sums of int data, derived from counts when
+	// intSumsCount values had been added.
+	intSums      []*big.Rat
+	intSumsCount int64
+}
+
+// parser turns input into Numbers of the configured kind and checks them
+// against the configured range.
+type parser struct {
+	opts Options
+	// minFloat and maxFloat are opts.Min and opts.Max for the fast paths.
+	minFloat float64
+	maxFloat float64
+}
+
+// Parser reads values the way an Accumulator with the same Options does,
+// without keeping them.
+type Parser struct {
+	parser
+}
+
+func NewParser(opts Options) (Parser, error) {
+	p, err := newParser(opts)
+	return Parser{p}, err
+}
+
+func NewAccumulator() *Accumulator {
+	acc, _ := New(DefaultOptions())
+	return acc
+}
+
+func newParser(opts Options) (parser, error) {
+	if opts.Min != nil && opts.Max != nil && opts.Min.Cmp(opts.Max) > 0 {
+		return parser{}, fmt.Errorf("empty range [%s:%s]", opts.Min.RatString(), opts.Max.RatString())
+	}
+	if opts.Kind != Int && opts.Kind != Float && opts.Kind != Decimal {
+		return parser{}, fmt.Errorf("unknown kind %d", opts.Kind)
+	}
+	p := parser{opts: opts, minFloat: math.Inf(-1), maxFloat: math.Inf(1)}
+	if opts.Min != nil {
+		p.minFloat, _ = opts.Min.Float64()
+	}
+	if opts.Max != nil {
+		p.maxFloat, _ = opts.Max.Float64()
+	}
+	return p, nil
+}
+
+func New(opts Options) (*Accumulator, error) {
+	p, err := newParser(opts)
+	if err != nil {
+		return nil, err
+	}
+	acc := &Accumulator{parser: p}
+	switch opts.Kind {
+	case Int:
+		acc.counts = newIntCounter(opts.Min, opts.Max)
+	case Float:
+		acc.counts = newFloatCounter()
+	case Decimal:
+		acc.counts = newRatCounter()
+		acc.sums = []*big.Rat{new(big.Rat), new(big.Rat), new(big.Rat), new(big.Rat)}
+	}
+	return acc, nil
+}
+
+func (p parser) Kind() Kind {
+	return p.opts.Kind
+}
+
+func (acc *Accumulator) Count() int64 {
+	return acc.count
+}
+
+func (acc *Accumulator) Add(num int32) error {
+	return acc.AddInt(int64(num))
+}
+
+func (acc *Accumulator) AddInt(num int64) error {
+	return acc.addChecked(acc.fromInt(num))
+}
+
+func (acc *Accumulator) AddFloat(num float64) error {
+	return acc.addChecked(acc.fromFloat(num))
+}
+
+func (acc *Accumulator) AddRat(num *big.Rat) error {
+	return acc.addChecked(acc.fromRat(new(big.Rat).Set(num)))
+}
+
+// AddString parses a value according to the accumulator's kind and adds it.
+func (acc *Accumulator) AddString(s string) error {
+	return acc.addChecked(acc.Parse(s))
+}
+
+// AddNumber adds a value returned by Parse, possibly from another
+// accumulator of a different kind.
+func (acc *Accumulator) AddNumber(num Number) error {
+	return acc.addChecked(acc.convert(num))
+}
+
+// AddWeighted adds a value that occurs weight times, as in pre-aggregated
+// value,count data. Every metric weighs it accordingly; a zero weight adds
+// nothing.
+func (acc *Accumulator) AddWeighted(num Number, weight uint64) error {
+	num, err := acc.convert(num)
+	if err != nil {
+		return err
+	}
+	if weight > MaxWeight {
+		return ErrTooLarge
+	}
+	if weight != 0 {
+		acc.addN(num, weight)
+	}
+	return nil
+}
+
+func (acc *Accumulator) addChecked(num Number, err error) error {
+	if err != nil {
+		return err
+	}
+	acc.add(num)
+	return nil
+}
+
+// convert checks a Number, possibly of another kind, against this parser.
+func (p parser) convert(num Number) (Number, error) {
+	if num.Exact != nil {
+		return p.fromRat(new(big.Rat).Set(num.Exact))
+	}
+	return p.fromFloat(num.Float)
+}
+
+// Parse reads a value according to the accumulator's kind and checks it
+// against the range without adding it, so a caller can validate a whole
+// record first. Integers go through strconv.Atoi, so errors read the same
+// as they always have.
+func (p parser) Parse(s string) (Number, error) {
+	switch p.opts.Kind {
+	case Float:
+		num, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Number{}, err
+		}
+		return p.fromFloat(num)
+	case Decimal:
+		num, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return Number{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		return p.fromRat(num)
+	}
+	num, err := strconv.Atoi(s)
+	if err != nil {
+		return Number{}, err
+	}
+	return p.fromInt(int64(num))
+}
+
+func (p parser) fromInt(num int64) (Number, error) {
+	switch p.opts.Kind {
+	case Float:
+		return p.fromFloat(float64(num))
+	case Decimal:
+		return p.fromRat(new(big.Rat).SetInt64(num))
+	}
+	if num > maxExactInt || num < -maxExactInt {
+		return Number{}, ErrTooLarge
+	}
+	if f := float64(num); f < p.minFloat || f > p.maxFloat {
+		return Number{}, p.rangeError()
+	}
+	return floatNumber(float64(num)), nil
+}
+
+func (p parser) fromFloat(num float64) (Number, error) {
+	if math.IsNaN(num) || math.IsInf(num, 0) {
+		return Number{}, ErrNotFinite
+	}
+	switch p.opts.Kind {
+	case Int:
+		if num != math.Trunc(num) {
+			return Number{}, ErrNotInteger
+		}
+		return p.fromInt(int64(num))
+	case Decimal:
+		return p.fromRat(new(big.Rat).SetFloat64(num))
+	}
+	if num < p.minFloat || num > p.maxFloat {
+		return Number{}, p.rangeError()
+	}
+	if num == 0 {
+		num = 0 // fold -0 into 0 so both count as the same value
+	}
+	return floatNumber(num), nil
+}
+
+// fromRat takes ownership of num.
+func (p parser) fromRat(num *big.Rat) (Number, error) {
+	switch p.opts.Kind {
+	case Int:
+		if !num.IsInt() {
+			return Number{}, ErrNotInteger
+		}
+		if !num.Num().IsInt64() {
+			return Number{}, ErrTooLarge
+		}
+		return p.fromInt(num.Num().Int64())
+	case Float:
+		f, _ := num.Float64()
+		return p.fromFloat(f)
+	}
+	if (p.opts.Min != nil && num.Cmp(p.opts.Min) < 0) || (p.opts.Max != nil && num.Cmp(p.opts.Max) > 0) {
+		return Number{}, p.rangeError()
+	}
+	return ratNumber(num), nil
+}
+
+func (p parser) rangeError() error {
+	return &RangeError{Min: p.opts.Min, Max: p.opts.Max}
+}
+
+func (acc *Accumulator) add(num Number) {
+	acc.count++
+	n := float64(acc.count)
+	delta := num.Float - acc.mean
+	deltaN := delta / n
+	term := delta * deltaN * (n - 1)
+	acc.m4 += term*deltaN*deltaN*(n*n-3*n+3) + 6*deltaN*deltaN*acc.m2 - 4*deltaN*acc.m3
+	acc.m3 += term*deltaN*(n-2) - 3*deltaN*acc.m2
+	acc.mean += delta / n
+	acc.m2 += delta * (num.Float - acc.mean)
+	acc.addSums(num, 1)
+	acc.counts.add(num, 1)
+}
+
+// addN adds weight copies of num at once, with the pairwise update of
+// Pébay (2008) for merging a sample with a constant one.
+func (acc *Accumulator) addN(num Number, weight uint64) {
+	if weight == 1 {
+		acc.add(num)
+		return
+	}
+	na, nb := float64(acc.count), float64(weight)
+	acc.count += int64(weight)
+	n := float64(acc.count)
+	delta := num.Float - acc.mean
+	deltaN := delta / n
+	acc.m4 += delta*deltaN*deltaN*deltaN*na*nb*(na*na-na*nb+nb*nb) +
+		6*deltaN*deltaN*nb*nb*acc.m2 - 4*deltaN*nb*acc.m3
+	acc.m3 += delta*deltaN*deltaN*na*nb*(na-nb) - 3*deltaN*nb*acc.m2
+	acc.m2 += delta * deltaN * na * nb
+	acc.mean += nb * deltaN
+	acc.addSums(num, weight)
+	acc.counts.add(num, weight)
+}
+
+// Merge adds everything other has seen, as if its values had been added to
+// acc after acc's own. The moments of float data are merged with the
+// pairwise update of Pébay (2008), so they depend on the order of merges
+// but not on how each side was computed; int and decimal data merge
+// exactly. Both must have been created with the same Options.
+func (acc *Accumulator) Merge(other *Accumulator) error {
+	if acc.opts.Kind != other.opts.Kind || !sameBound(acc.opts.Min, other.opts.Min) || !sameBound(acc.opts.Max, other.opts.Max) {
+		return errors.New("cannot merge accumulators with different options")
+	}
+	if other.count == 0 {
+		return nil
+	}
+	if acc.count == 0 {
+		acc.mean, acc.m2, acc.m3, acc.m4 = other.mean, other.m2, other.m3, other.m4
+	} else {
+		na, nb := float64(acc.count), float64(other.count)
+		n := na + nb
+		delta := other.mean - acc.mean
+		deltaN := delta / n
+		acc.m4 += other.m4 + delta*deltaN*deltaN*deltaN*na*nb*(na*na-na*nb+nb*nb) +
+			6*deltaN*deltaN*(na*na*other.m2+nb*nb*acc.m2) + 4*deltaN*(na*other.m3-nb*acc.m3)
+		acc.m3 += other.m3 + delta*deltaN*deltaN*na*nb*(na-nb) + 3*deltaN*(na*other.m2-nb*acc.m2)
+		acc.m2 += other.m2 + delta*deltaN*na*nb
+		acc.mean += nb * deltaN
+	}
+	acc.count += other.count
+	for i, sum := range acc.sums {
+		sum.Add(sum, other.sums[i])
+	}
+	other.counts.each(func(num Number, count uint64) bool {
+		acc.counts.add(num, count)
+		return true
+	})
+	return nil
+}
+
+func sameBound(a, b *big.Rat) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+// addSums adds weight·x, weight·x², weight·x³ and weight·x⁴ to the exact
+// power sums of decimal data.
+func (acc *Accumulator) addSums(num Number, weight uint64) {
+	if acc.sums == nil {
+		return
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).SetUint64(weight))
+	power := new(big.Rat).Set(num.Exact)
+	for i, sum := range acc.sums {
+		if i > 0 {
+			power.Mul(power, num.Exact)
+		}
+		sum.Add(sum, new(big.Rat).Mul(power, scale))
+	}
+}
+
+// powerSums returns the exact sums of x, x², x³ and x⁴, or nil for float
+// data. The caller must not modify them.
+func (acc *Accumulator) powerSums() []*big.Rat {
+	switch acc.opts.Kind {
+	case Decimal:
+		return acc.sums
+	case Float:
+		return nil
+	}
+	if acc.intSums != nil && acc.intSumsCount == acc.count {
+		return acc.intSums
+	}
+	sums := []*big.Int{new(big.Int), new(big.Int), new(big.Int), new(big.Int)}
+	x, term := new(big.Int), new(big.Int)
+	acc.counts.each(func(num Number, count uint64) bool {
+		x.SetInt64(int64(num.Float))
+		term.SetUint64(count)
+		for _, sum := range sums {
+			term.Mul(term, x)
+			sum.Add(sum, term)
+		}
+		return true
+	})
+	acc.intSums = make([]*big.Rat, len(sums))
+	for i, sum := range sums {
+		acc.intSums[i] = new(big.Rat).SetInt(sum)
+	}
+	acc.intSumsCount = acc.count
+	return acc.intSums
+}
+
+// exactNumber is a value computed from the power sums. Int data prints its
+// derived values as floats, so it gets one, correctly rounded.
+func (acc *Accumulator) exactNumber(r *big.Rat) Number {
+	if acc.opts.Kind == Int {
+		f, _ := r.Float64()
+		return floatNumber(f)
+	}
+	return ratNumber(r)
+}
+
+// nth returns the k-th smallest value (0-based) seen so far.
+func (acc *Accumulator) nth(k int64) Number {
+	var seen int64
+	var found Number
+	acc.counts.each(func(num Number, count uint64) bool {
+		seen += int64(count)
+		found = num
+		return seen <= k
+	})
+	return found
+}
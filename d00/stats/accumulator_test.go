@@ -0,0 +1,34 @@
+package stats
+
+import "testing"
+
+// FuzzParse checks that every value Parse accepts can be added, and prints
+// as something that parses back to the same value.
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"0", "-0", "42", "-100000", "100001", "0.1", "1e5", "1/3", "NaN", "+Inf", " 1", "0x10", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, kind := range []Kind{Int, Float, Decimal} {
+			opts := DefaultOptions()
+			opts.Kind = kind
+			acc, err := New(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			num, err := acc.Parse(s)
+			if err != nil {
+				continue
+			}
+			if err := acc.AddNumber(num); err != nil {
+				t.Errorf("kind %d: %q parses but cannot be added: %v", kind, s, err)
+			}
+			again, err := acc.Parse(num.String())
+			if err != nil {
+				t.Errorf("kind %d: %q prints as %q, which does not parse: %v", kind, s, num, err)
+			} else if compareNumbers(again, num) != 0 {
+				t.Errorf("kind %d: %q prints as %q, which parses as %v", kind, s, num, again)
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package stats
+
+import "math"
+
+// Test is the result of a two-sample test. Statistic is named Symbol, and
+// DF is only set for the t-test. PValue is two-sided.
+type Test struct {
+	Name      string
+	Label     string
+	Symbol    string
+	Statistic float64
+	DF        float64
+	PValue    float64
+}
+
+// Compare runs every two-sample test on a and b: Welch's t-test,
+// Mann–Whitney U and two-sample Kolmogorov–Smirnov.
+func Compare(a, b *Accumulator) ([]Test, error) {
+	if a.count == 0 || b.count == 0 {
+		return nil, ErrEmpty
+	}
+	return []Test{WelchT(a, b), MannWhitney(a, b), KolmogorovSmirnov(a, b)}, nil
+}
+
+// WelchT tests whether the means differ without assuming equal variances,
+// with the Welch–Satterthwaite degrees of freedom. It is NaN unless both
+// samples hold at least two values.
+func WelchT(a, b *Accumulator) Test {
+	na, nb := float64(a.count), float64(b.count)
+	va, vb := SampleVariance(a).Float/na, SampleVariance(b).Float/nb
+	t := (Mean(a).Float - Mean(b).Float) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/(na-1) + vb*vb/(nb-1))
+	return Test{Name: "welch", Label: "Welch t-test", Symbol: "t", Statistic: t, DF: df,
+		PValue: 2 * studentCDF(-math.Abs(t), df)}
+}
+
+// MannWhitney is the U statistic of a, with tied values sharing their
+// average rank. The p-value uses the normal approximation with the tie and
+// continuity corrections.
+func MannWhitney(a, b *Accumulator) Test {
+	na, nb := float64(a.count), float64(b.count)
+	n := na + nb
+	var seen, rankSum, ties float64
+	mergeCounts(a, b, func(countA, countB uint64) {
+		tied := float64(countA + countB)
+		rankSum += float64(countA) * (seen + (tied+1)/2)
+		ties += tied*tied*tied - tied
+		seen += tied
+	})
+	u := rankSum - na*(na+1)/2
+	sigma := math.Sqrt(na * nb / 12 * (n + 1 - ties/(n*(n-1))))
+	z := math.Max(math.Abs(u-na*nb/2)-0.5, 0) / sigma
+	return Test{Name: "mannwhitney", Label: "Mann-Whitney U", Symbol: "U", Statistic: u,
+		PValue: math.Erfc(z / math.Sqrt2)}
+}
+
+// KolmogorovSmirnov is the largest distance between the empirical CDFs of
+// a and b. The p-value is the asymptotic one of Numerical Recipes §14.3.
+func KolmogorovSmirnov(a, b *Accumulator) Test {
+	na, nb := float64(a.count), float64(b.count)
+	var seenA, seenB, d float64
+	mergeCounts(a, b, func(countA, countB uint64) {
+		seenA += float64(countA)
+		seenB += float64(countB)
+		d = math.Max(d, math.Abs(seenA/na-seenB/nb))
+	})
+	en := math.Sqrt(na * nb / (na + nb))
+	return Test{Name: "ks", Label: "Kolmogorov-Smirnov", Symbol: "D", Statistic: d,
+		PValue: kolmogorovQ((en + 0.12 + 0.11/en) * d)}
+}
+
+// kolmogorovQ is the tail probability of the Kolmogorov distribution.
+func kolmogorovQ(lambda float64) float64 {
+	if lambda < 1e-3 {
+		return 1
+	}
+	var sum, sign float64 = 0, 2
+	for j := 1; j <= 100; j++ {
+		term := sign * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-12*math.Abs(sum) {
+			return math.Min(math.Max(sum, 0), 1)
+		}
+		sign = -sign
+	}
+	return 1
+}
+
+// mergeCounts walks the distinct values of a and b together in ascending
+// order, calling fn with how many times each occurs in either.
+func mergeCounts(a, b *Accumulator, fn func(countA, countB uint64)) {
+	var values []Frequency
+	b.counts.each(func(num Number, count uint64) bool {
+		values = append(values, Frequency{Value: num, Count: count})
+		return true
+	})
+	next := 0
+	a.counts.each(func(num Number, count uint64) bool {
+		for ; next < len(values) && compareNumbers(values[next].Value, num) < 0; next++ {
+			fn(0, values[next].Count)
+		}
+		if next < len(values) && compareNumbers(values[next].Value, num) == 0 {
+			fn(count, values[next].Count)
+			next++
+		} else {
+			fn(count, 0)
+		}
+		return true
+	})
+	for ; next < len(values); next++ {
+		fn(0, values[next].Count)
+	}
+}
@@ -0,0 +1,127 @@
+package stats
+
+import (
+	"math/big"
+	"sort"
+)
+
+// counter counts how many times each distinct value occurs and walks them
+// in ascending order. each stops as soon as fn returns false.
+type counter interface {
+	add(num Number, count uint64)
+	each(fn func(num Number, count uint64) bool)
+}
+
+// newIntCounter uses a flat histogram when the range is bounded and narrow
+// enough, which keeps memory constant, and a map otherwise.
+func newIntCounter(min, max *big.Rat) counter {
+	if min == nil || max == nil {
+		return newFloatCounter()
+	}
+	low := new(big.Int).Quo(min.Num(), min.Denom())
+	if min.Sign() > 0 && !min.IsInt() {
+		low.Add(low, big.NewInt(1))
+	}
+	high := new(big.Int).Quo(max.Num(), max.Denom())
+	if max.Sign() < 0 && !max.IsInt() {
+		high.Sub(high, big.NewInt(1))
+	}
+	width := new(big.Int).Sub(high, low)
+	if !width.IsInt64() || width.Int64() >= denseLimit {
+		return newFloatCounter()
+	}
+	return &denseCounter{low: low.Int64(), counts: make([]uint64, width.Int64()+1)}
+}
+
+type denseCounter struct {
+	low    int64
+	counts []uint64
+}
+
+func (c *denseCounter) add(num Number, count uint64) {
+	c.counts[int64(num.Float)-c.low] += count
+}
+
+func (c *denseCounter) each(fn func(Number, uint64) bool) {
+	for i, count := range c.counts {
+		if count != 0 && !fn(floatNumber(float64(int64(i)+c.low)), count) {
+			return
+		}
+	}
+}
+
+// floatCounter also stores integers, which are exact in a float64 up to
+// maxExactInt.
+type floatCounter struct {
+	counts map[float64]uint64
+	sorted []float64
+}
+
+func newFloatCounter() *floatCounter {
+	return &floatCounter{counts: make(map[float64]uint64)}
+}
+
+func (c *floatCounter) add(num Number, count uint64) {
+	if _, ok := c.counts[num.Float]; !ok {
+		c.sorted = nil
+	}
+	c.counts[num.Float] += count
+}
+
+func (c *floatCounter) each(fn func(Number, uint64) bool) {
+	if c.sorted == nil {
+		c.sorted = make([]float64, 0, len(c.counts))
+		for key := range c.counts {
+			c.sorted = append(c.sorted, key)
+		}
+		sort.Float64s(c.sorted)
+	}
+	for _, key := range c.sorted {
+		if !fn(floatNumber(key), c.counts[key]) {
+			return
+		}
+	}
+}
+
+type ratCount struct {
+	value *big.Rat
+	count uint64
+}
+
+// ratCounter keys exact values by their canonical "a/b" form.
+type ratCounter struct {
+	counts map[string]*ratCount
+	sorted []*ratCount
+}
+
+func newRatCounter() *ratCounter {
+	return &ratCounter{counts: make(map[string]*ratCount)}
+}
+
+func (c *ratCounter) add(num Number, count uint64) {
+	key := num.Exact.RatString()
+	entry, ok := c.counts[key]
+	if !ok {
+		entry = &ratCount{value: num.Exact}
+		c.counts[key] = entry
+		c.sorted = nil
+	}
+	entry.count += count
+}
+
+func (c *ratCounter) each(fn func(Number, uint64) bool) {
+	if c.sorted == nil {
+		c.sorted = make([]*ratCount, 0, len(c.counts))
+		for _, entry := range c.counts {
+			c.sorted = append(c.sorted, entry)
+		}
+		sort.Slice(c.sorted, func(i, j int) bool {
+			return c.sorted[i].value.Cmp(c.sorted[j].value) < 0
+		})
+	}
+	for _, entry := range c.sorted {
+		if !fn(ratNumber(entry.value), entry.count) {
+			return
+		}
+	}
+}
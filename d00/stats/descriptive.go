@@ -0,0 +1,268 @@
+package stats
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+func init() {
+	RegisterDefault(NewMetric("mean", "Mean", Mean))
+	RegisterDefault(NewMetric("median", "Median", Median))
+	RegisterDefault(NewDiscreteMetric("mode", "Mode", Mode))
+	RegisterDefault(NewMetric("sd", "SD", SD))
+	Register(NewDiscreteMetric("minimum", "Minimum", Min))
+	Register(NewDiscreteMetric("maximum", "Maximum", Max))
+	Register(rangeMetric{NewMetric("range", "Range", Range)})
+	Register(NewMetric("iqr", "IQR", IQR))
+	Register(NewMetric("var", "Variance", Variance))
+	Register(NewMetric("svar", "Sample variance", SampleVariance))
+	Register(NewMetric("skew", "Skewness", Skewness))
+	Register(NewMetric("kurt", "Kurtosis", Kurtosis))
+	Register(NewMetric("cv", "CV", CV))
+	Register(NewMetric("mad", "MAD", MAD))
+}
+
+func Mean(acc *Accumulator) Number {
+	if sums := acc.powerSums(); sums != nil {
+		return acc.exactNumber(new(big.Rat).Quo(sums[0], acc.n()))
+	}
+	return floatNumber(acc.mean)
+}
+
+func Median(acc *Accumulator) Number {
+	if acc.count%2 == 0 {
+		return midpoint(acc.nth(acc.count/2), acc.nth(acc.count/2-1))
+	}
+	return acc.nth(acc.count / 2)
+}
+
+// ExactMean is the mean as a fraction whatever the kind of the data, since
+// float values are exact binary fractions. It is nil for no data.
+func ExactMean(acc *Accumulator) *big.Rat {
+	if acc.count == 0 {
+		return nil
+	}
+	if sums := acc.powerSums(); sums != nil {
+		return new(big.Rat).Quo(sums[0], acc.n())
+	}
+	sum := new(big.Rat)
+	acc.counts.each(func(num Number, count uint64) bool {
+		term := new(big.Rat).SetFloat64(num.Float)
+		sum.Add(sum, term.Mul(term, new(big.Rat).SetInt(new(big.Int).SetUint64(count))))
+		return true
+	})
+	return sum.Quo(sum, acc.n())
+}
+
+// ExactMedian is Median as a fraction, nil for no data.
+func ExactMedian(acc *Accumulator) *big.Rat {
+	if acc.count == 0 {
+		return nil
+	}
+	exact := func(num Number) *big.Rat {
+		if num.Exact != nil {
+			return num.Exact
+		}
+		return new(big.Rat).SetFloat64(num.Float)
+	}
+	return midpoint(ratNumber(exact(acc.nth((acc.count-1)/2))), ratNumber(exact(acc.nth(acc.count/2)))).Exact
+}
+
+// Mode returns the most frequent value, the smallest one on ties.
+func Mode(acc *Accumulator) Number {
+	var maxCount uint64
+	var mode Number
+	acc.counts.each(func(num Number, count uint64) bool {
+		if count > maxCount {
+			maxCount = count
+			mode = num
+		}
+		return true
+	})
+	return mode
+}
+
+// SD is the population standard deviation.
+func SD(acc *Accumulator) Number {
+	return sqrtNumber(Variance(acc))
+}
+
+func Min(acc *Accumulator) Number {
+	return acc.nth(0)
+}
+
+func Max(acc *Accumulator) Number {
+	return acc.nth(acc.count - 1)
+}
+
+func Range(acc *Accumulator) Number {
+	return subNumbers(Max(acc), Min(acc))
+}
+
+// rangeMetric is discrete for int and decimal data, whose range is exact
+// and printed like an input value. The range of floats is rounded like any
+// other derived value.
+type rangeMetric struct {
+	Metric
+}
+
+func (m rangeMetric) Compute(acc *Accumulator) Result {
+	result := m.Metric.Compute(acc)
+	result.Discrete = acc.Kind() != Float
+	return result
+}
+
+// Quantile returns the p-quantile (0 <= p <= 1), linearly interpolating
+// between the two closest ranks. Quantile(acc, 0.5) equals Median(acc).
+func Quantile(acc *Accumulator, p float64) Number {
+	h := float64(acc.count-1) * p
+	lower := int64(math.Floor(h))
+	low := acc.nth(lower)
+	if lower+1 >= acc.count {
+		return low
+	}
+	high := acc.nth(lower + 1)
+	if low.Exact != nil {
+		// Redo the interpolation with p read as the decimal it was
+		// written as, not its binary approximation.
+		exactP, _ := new(big.Rat).SetString(strconv.FormatFloat(p, 'f', -1, 64))
+		frac := exactP.Mul(exactP, big.NewRat(acc.count-1, 1))
+		frac.Sub(frac, big.NewRat(lower, 1))
+		step := new(big.Rat).Sub(high.Exact, low.Exact)
+		return ratNumber(step.Add(step.Mul(step, frac), low.Exact))
+	}
+	return floatNumber(low.Float + (h-float64(lower))*(high.Float-low.Float))
+}
+
+// IQR is the interquartile range.
+func IQR(acc *Accumulator) Number {
+	return subNumbers(Quantile(acc, 0.75), Quantile(acc, 0.25))
+}
+
+// MAD is the median absolute deviation from the median.
+func MAD(acc *Accumulator) Number {
+	median := Median(acc)
+	type deviation struct {
+		value Number
+		count uint64
+	}
+	var deviations []deviation
+	acc.counts.each(func(num Number, count uint64) bool {
+		diff := subNumbers(num, median)
+		if diff.Float < 0 {
+			diff = subNumbers(median, num)
+		}
+		deviations = append(deviations, deviation{value: diff, count: count})
+		return true
+	})
+	sort.Slice(deviations, func(i, j int) bool {
+		return compareNumbers(deviations[i].value, deviations[j].value) < 0
+	})
+	nth := func(k int64) Number {
+		var seen int64
+		for _, dev := range deviations {
+			seen += int64(dev.count)
+			if seen > k {
+				return dev.value
+			}
+		}
+		return deviations[len(deviations)-1].value
+	}
+	if acc.count%2 == 0 {
+		return midpoint(nth(acc.count/2), nth(acc.count/2-1))
+	}
+	return nth(acc.count / 2)
+}
+
+// Variance is the population variance.
+func Variance(acc *Accumulator) Number {
+	if acc.powerSums() != nil {
+		return acc.exactNumber(new(big.Rat).Quo(acc.centralMoment(2), acc.n()))
+	}
+	return floatNumber(acc.m2 / float64(acc.count))
+}
+
+// SampleVariance is the unbiased variance, NaN for a single value.
+func SampleVariance(acc *Accumulator) Number {
+	if acc.powerSums() != nil && acc.count > 1 {
+		return acc.exactNumber(new(big.Rat).Quo(acc.centralMoment(2), big.NewRat(acc.count-1, 1)))
+	}
+	return floatNumber(acc.m2 / float64(acc.count-1))
+}
+
+// Skewness is the population skewness g1, NaN when all values are equal.
+func Skewness(acc *Accumulator) Number {
+	if acc.powerSums() != nil {
+		m2, m3 := acc.centralMoment(2), acc.centralMoment(3)
+		if m2.Sign() == 0 {
+			return floatNumber(math.NaN())
+		}
+		// g1 = sqrt(n) * m3 / m2^1.5 = m3 * sqrt(n / m2³)
+		cube := new(big.Rat).Mul(m2, m2)
+		cube.Mul(cube, m2)
+		root := sqrtNumber(ratNumber(cube.Quo(acc.n(), cube)))
+		return acc.exactNumber(new(big.Rat).Mul(m3, root.Exact))
+	}
+	return floatNumber(math.Sqrt(float64(acc.count)) * acc.m3 / math.Pow(acc.m2, 1.5))
+}
+
+// Kurtosis is the population excess kurtosis g2, NaN when all values are
+// equal.
+func Kurtosis(acc *Accumulator) Number {
+	if acc.powerSums() != nil {
+		m2, m4 := acc.centralMoment(2), acc.centralMoment(4)
+		if m2.Sign() == 0 {
+			return floatNumber(math.NaN())
+		}
+		g2 := new(big.Rat).Mul(acc.n(), m4)
+		g2.Quo(g2, new(big.Rat).Mul(m2, m2))
+		return acc.exactNumber(g2.Sub(g2, big.NewRat(3, 1)))
+	}
+	return floatNumber(float64(acc.count)*acc.m4/(acc.m2*acc.m2) - 3)
+}
+
+// CV is the coefficient of variation, the population SD relative to the
+// mean.
+func CV(acc *Accumulator) Number {
+	return quoNumbers(SD(acc), Mean(acc))
+}
+
+// PercentileMetric returns a metric for the p-th percentile (0 <= p <= 100),
+// named after it like "p95".
+func PercentileMetric(p float64) Metric {
+	name := strconv.FormatFloat(p, 'f', -1, 64)
+	return NewMetric("p"+name, "P"+name, func(acc *Accumulator) Number {
+		return Quantile(acc, p/100)
+	})
+}
+
+func (acc *Accumulator) n() *big.Rat {
+	return big.NewRat(acc.count, 1)
+}
+
+// centralMoment returns the exact sum of (x - mean)^k, k in 2..4, expanded
+// from the power sums of int or decimal data.
+func (acc *Accumulator) centralMoment(k int) *big.Rat {
+	sums := acc.powerSums()
+	n := acc.n()
+	mean := new(big.Rat).Quo(sums[0], n)
+	// sum (x - m)^k = sum_j C(k,j) (-m)^(k-j) S_j, with S_0 = n
+	binomial := [][]int64{2: {1, 2, 1}, 3: {1, 3, 3, 1}, 4: {1, 4, 6, 4, 1}}[k]
+	negMean := new(big.Rat).Neg(mean)
+	moment := new(big.Rat)
+	for j := 0; j <= k; j++ {
+		term := new(big.Rat).SetInt64(binomial[j])
+		for i := 0; i < k-j; i++ {
+			term.Mul(term, negMean)
+		}
+		if j == 0 {
+			term.Mul(term, n)
+		} else {
+			term.Mul(term, sums[j-1])
+		}
+		moment.Add(moment, term)
+	}
+	return moment
+}
@@ -0,0 +1,212 @@
+package stats
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// reference computes the metrics the obvious way, on a sorted copy of the
+// data, for the property tests to check the single-pass ones against.
+type reference struct {
+	sorted []float64
+}
+
+func newReference(data []float64) reference {
+	sorted := append([]float64(nil), data...)
+	sort.Float64s(sorted)
+	return reference{sorted: sorted}
+}
+
+func (ref reference) mean() float64 {
+	var sum float64
+	for _, x := range ref.sorted {
+		sum += x
+	}
+	return sum / float64(len(ref.sorted))
+}
+
+func (ref reference) median() float64 {
+	n := len(ref.sorted)
+	if n%2 == 0 {
+		return (ref.sorted[n/2-1] + ref.sorted[n/2]) / 2
+	}
+	return ref.sorted[n/2]
+}
+
+// mode is the smallest of the most frequent values.
+func (ref reference) mode() float64 {
+	best, bestCount := ref.sorted[0], 0
+	for i := 0; i < len(ref.sorted); {
+		j := i
+		for j < len(ref.sorted) && ref.sorted[j] == ref.sorted[i] {
+			j++
+		}
+		if j-i > bestCount {
+			best, bestCount = ref.sorted[i], j-i
+		}
+		i = j
+	}
+	return best
+}
+
+// centralMoment is the k-th central moment, divided by n.
+func (ref reference) centralMoment(k int) float64 {
+	mean := ref.mean()
+	var sum float64
+	for _, x := range ref.sorted {
+		sum += math.Pow(x-mean, float64(k))
+	}
+	return sum / float64(len(ref.sorted))
+}
+
+func (ref reference) quantile(p float64) float64 {
+	h := float64(len(ref.sorted)-1) * p
+	lower := int(math.Floor(h))
+	if lower+1 >= len(ref.sorted) {
+		return ref.sorted[lower]
+	}
+	return ref.sorted[lower] + (h-float64(lower))*(ref.sorted[lower+1]-ref.sorted[lower])
+}
+
+// randomData draws n integers from a range narrow enough to give ties.
+func randomData(rng *rand.Rand, n, spread int) []float64 {
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = float64(rng.Intn(2*spread+1) - spread)
+	}
+	return data
+}
+
+func accumulate(t *testing.T, opts Options, data []float64) *Accumulator {
+	t.Helper()
+	acc, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range data {
+		if err := acc.AddFloat(x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return acc
+}
+
+func closeTo(got, want float64) bool {
+	if math.IsNaN(want) {
+		return math.IsNaN(got)
+	}
+	return math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want))
+}
+
+func TestMetricsMatchReference(t *testing.T) {
+	type check struct {
+		name      string
+		got, want float64
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, kind := range []Kind{Int, Float, Decimal} {
+		opts := DefaultOptions()
+		opts.Kind = kind
+		for trial := 0; trial < 200; trial++ {
+			data := randomData(rng, 1+rng.Intn(60), 1+rng.Intn(30))
+			acc := accumulate(t, opts, data)
+			ref := newReference(data)
+			variance := ref.centralMoment(2)
+			checks := []check{
+				{"mean", Mean(acc).Float, ref.mean()},
+				{"median", Median(acc).Float, ref.median()},
+				{"mode", Mode(acc).Float, ref.mode()},
+				{"min", Min(acc).Float, ref.sorted[0]},
+				{"max", Max(acc).Float, ref.sorted[len(ref.sorted)-1]},
+				{"variance", Variance(acc).Float, variance},
+				{"sd", SD(acc).Float, math.Sqrt(variance)},
+				{"p10", Quantile(acc, 0.1).Float, ref.quantile(0.1)},
+				{"p75", Quantile(acc, 0.75).Float, ref.quantile(0.75)},
+			}
+			if variance > 0 {
+				checks = append(checks,
+					check{"skew", Skewness(acc).Float, ref.centralMoment(3) / math.Pow(variance, 1.5)},
+					check{"kurt", Kurtosis(acc).Float, ref.centralMoment(4)/(variance*variance) - 3})
+			}
+			for _, c := range checks {
+				if !closeTo(c.got, c.want) {
+					t.Errorf("kind %d, %v: %s = %v, want %v", kind, data, c.name, c.got, c.want)
+				}
+			}
+		}
+	}
+}
+
+func TestMedianAndModeEdgeCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []float64
+		median float64
+		mode   float64
+	}{
+		{"single element", []float64{7}, 7, 7},
+		{"even length", []float64{4, 1, 3, 2}, 2.5, 1},
+		{"odd length", []float64{5, 1, 3}, 3, 1},
+		{"tied modes take the smallest", []float64{3, 3, 1, 1, 2}, 2, 1},
+		{"negative values", []float64{-5, -1, -1, -3}, -2, -1},
+		{"all equal", []float64{2, 2, 2, 2}, 2, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			acc := accumulate(t, DefaultOptions(), test.data)
+			if got := Median(acc).Float; got != test.median {
+				t.Errorf("Median = %v, want %v", got, test.median)
+			}
+			if got := Mode(acc).Float; got != test.mode {
+				t.Errorf("Mode = %v, want %v", got, test.mode)
+			}
+		})
+	}
+}
+
+func TestExactMeanAndMedian(t *testing.T) {
+	acc := accumulate(t, DefaultOptions(), []float64{1, 2, 2, 4})
+	if got := ExactMean(acc); got.Cmp(big.NewRat(9, 4)) != 0 {
+		t.Errorf("ExactMean = %v, want 9/4", got)
+	}
+	if got := ExactMedian(acc); got.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("ExactMedian = %v, want 2", got)
+	}
+	if ExactMean(NewAccumulator()) != nil || ExactMedian(NewAccumulator()) != nil {
+		t.Error("exact metrics of no data are not nil")
+	}
+}
+
+func TestMergeMatchesSinglePass(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for trial := 0; trial < 100; trial++ {
+		data := randomData(rng, 2+rng.Intn(100), 1000)
+		cut := rng.Intn(len(data) + 1)
+		whole := accumulate(t, DefaultOptions(), data)
+		merged := accumulate(t, DefaultOptions(), data[:cut])
+		if err := merged.Merge(accumulate(t, DefaultOptions(), data[cut:])); err != nil {
+			t.Fatal(err)
+		}
+		if merged.Count() != whole.Count() {
+			t.Fatalf("Count = %d, want %d", merged.Count(), whole.Count())
+		}
+		for _, metric := range Metrics() {
+			got, want := metric.Compute(merged).Value, metric.Compute(whole).Value
+			if !closeTo(got, want) {
+				t.Errorf("%v cut at %d: %s = %v, want %v", data, cut, metric.Name(), got, want)
+			}
+		}
+	}
+}
+
+func TestMergeRejectsOtherOptions(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Kind = Float
+	other, _ := New(opts)
+	if err := NewAccumulator().Merge(other); err == nil {
+		t.Error("merging a float accumulator into an int one succeeded")
+	}
+}
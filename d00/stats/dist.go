@@ -0,0 +1,106 @@
+package stats
+
+import "math"
+
+// Distribution functions used by the confidence intervals. They are
+// accurate to well beyond the two decimals d00 prints.
+
+const (
+	betaIterations = 300
+	betaEpsilon    = 1e-15
+)
+
+// studentCDF is P(T <= t) for Student's t distribution with df degrees of
+// freedom.
+func studentCDF(t, df float64) float64 {
+	if math.IsNaN(t) || math.IsNaN(df) || df <= 0 {
+		return math.NaN()
+	}
+	if math.IsInf(t, 0) {
+		if t > 0 {
+			return 1
+		}
+		return 0
+	}
+	tail := regIncBeta(df/2, 0.5, df/(df+t*t)) / 2
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// studentQuantile inverts studentCDF by bisection, which is slow next to
+// Newton's method but cannot diverge in the heavy tails of small df.
+func studentQuantile(p, df float64) float64 {
+	if math.IsNaN(p) || p <= 0 || p >= 1 || !(df > 0) {
+		return math.NaN()
+	}
+	low, high := -1.0, 1.0
+	for studentCDF(low, df) > p {
+		low *= 2
+	}
+	for studentCDF(high, df) < p {
+		high *= 2
+	}
+	for i := 0; i < 200 && high-low > 1e-12*math.Max(1, math.Abs(low)); i++ {
+		mid := (low + high) / 2
+		if studentCDF(mid, df) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with the continued fraction of Numerical Recipes §6.4.
+func regIncBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	lgA, _ := math.Lgamma(a)
+	lgB, _ := math.Lgamma(b)
+	lgAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgAB - lgA - lgB + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, 1-x)/b
+}
+
+// betaFraction evaluates the continued fraction with Lentz's method.
+func betaFraction(a, b, x float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= betaIterations; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < betaEpsilon {
+			break
+		}
+	}
+	return h
+}
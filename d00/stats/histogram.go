@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// maxAutoBins caps the bin count picked by the binning rules, which a few
+// far outliers can otherwise blow up.
+const maxAutoBins = 200
+
+// MaxBins is the most bins an explicit count may ask for.
+const MaxBins = 10000
+
+// Bin counts the values in [Low:High). The last bin of a histogram also
+// holds values equal to its High.
+type Bin struct {
+	Low   float64
+	High  float64
+	Count uint64
+}
+
+// Binning picks how many bins a histogram of acc gets.
+type Binning func(acc *Accumulator) int
+
+// ParseBinning reads "sturges", "fd" (Freedman–Diaconis) or an explicit
+// bin count from 1 to MaxBins.
+func ParseBinning(s string) (Binning, error) {
+	switch s {
+	case "sturges":
+		return Sturges, nil
+	case "fd":
+		return FreedmanDiaconis, nil
+	}
+	bins, err := strconv.Atoi(s)
+	if err != nil || bins < 1 {
+		return nil, fmt.Errorf("bins must be sturges, fd or a positive number: %q", s)
+	}
+	if bins > MaxBins {
+		return nil, fmt.Errorf("bins must be at most %d: %q", MaxBins, s)
+	}
+	return func(*Accumulator) int { return bins }, nil
+}
+
+// Sturges uses log2(n) + 1 bins, which suits roughly normal data.
+func Sturges(acc *Accumulator) int {
+	return int(math.Ceil(math.Log2(float64(acc.count)))) + 1
+}
+
+// FreedmanDiaconis uses bins of width 2·IQR/∛n, which copes better with
+// skewed data. It falls back to Sturges when the IQR is zero.
+func FreedmanDiaconis(acc *Accumulator) int {
+	width := 2 * IQR(acc).Float / math.Cbrt(float64(acc.count))
+	if width == 0 {
+		return Sturges(acc)
+	}
+	bins := int(math.Ceil(Range(acc).Float / width))
+	if bins < 1 {
+		return 1
+	}
+	if bins > maxAutoBins {
+		return maxAutoBins
+	}
+	return bins
+}
+
+// Histogram splits [Min:Max] into equal-width bins and counts the values in
+// each. Constant data gets a single bin. The width is computed from half the
+// range, which stays finite even when Max-Min overflows.
+func Histogram(acc *Accumulator, binning Binning) ([]Bin, error) {
+	if acc.count == 0 {
+		return nil, ErrEmpty
+	}
+	low, high := Min(acc).Float, Max(acc).Float
+	bins := binning(acc)
+	if low == high {
+		bins = 1
+	}
+	halfWidth := (high/2 - low/2) / float64(bins)
+	if low != high && (halfWidth == 0 || math.IsInf(halfWidth, 0) || math.IsNaN(halfWidth)) {
+		return nil, fmt.Errorf("cannot split [%g:%g] into %d bins", low, high, bins)
+	}
+	hist := make([]Bin, bins)
+	for i := range hist {
+		hist[i].Low = edge(low, halfWidth, i)
+		hist[i].High = edge(low, halfWidth, i+1)
+	}
+	hist[bins-1].High = high
+	acc.counts.each(func(num Number, count uint64) bool {
+		i := bins - 1
+		if halfWidth != 0 {
+			i = binIndex((num.Float/2-low/2)/halfWidth, bins)
+		}
+		hist[i].Count += count
+		return true
+	})
+	return hist, nil
+}
+
+// edge is the low edge of bin i, adding the offset in two halves so that
+// no step goes past the high end of the range.
+func edge(low, halfWidth float64, i int) float64 {
+	offset := float64(i) * halfWidth
+	return low + offset + offset
+}
+
+// binIndex clamps a bin position to [0:bins-1], since rounding can put
+// the extremes just outside.
+func binIndex(position float64, bins int) int {
+	switch {
+	case !(position >= 0):
+		return 0
+	case position >= float64(bins):
+		return bins - 1
+	}
+	return int(position)
+}
@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Interval is a confidence interval for a metric at a confidence level in
+// (0:1). Method tells how it was estimated: "t" or "bootstrap".
+type Interval struct {
+	Name   string
+	Label  string
+	Method string
+	Level  float64
+	Low    float64
+	High   float64
+}
+
+// MeanCI is the t-based confidence interval for the mean, built from the
+// sample SD. It is NaN for a single value.
+func MeanCI(acc *Accumulator, level float64) Interval {
+	mean := Mean(acc).Float
+	n := float64(acc.count)
+	margin := studentQuantile((1+level)/2, n-1) * math.Sqrt(SampleVariance(acc).Float/n)
+	return Interval{Name: "mean", Label: "Mean", Method: "t", Level: level, Low: mean - margin, High: mean + margin}
+}
+
+// BootstrapOptions configure Bootstrap. Every resample gets its own random
+// source derived from Seed, so the result does not depend on Workers.
+type BootstrapOptions struct {
+	Resamples int
+	Seed      int64
+	// Workers defaults to the number of CPUs.
+	Workers int
+}
+
+// Bootstrap estimates percentile confidence intervals for metrics by
+// recomputing them on resamples of the data drawn with replacement. Every
+// metric is computed on the same resamples.
+func Bootstrap(acc *Accumulator, level float64, opts BootstrapOptions, metrics ...Metric) ([]Interval, error) {
+	if acc.count == 0 {
+		return nil, ErrEmpty
+	}
+	if opts.Resamples < 1 {
+		return nil, fmt.Errorf("resamples must be positive, got %d", opts.Resamples)
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	var values []Number
+	var cumulative []int64
+	var seen int64
+	acc.counts.each(func(num Number, count uint64) bool {
+		seen += int64(count)
+		values = append(values, num)
+		cumulative = append(cumulative, seen)
+		return true
+	})
+	estimates := make([][]float64, len(metrics))
+	for i := range estimates {
+		estimates[i] = make([]float64, opts.Resamples)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			draws := make([]uint64, len(values))
+			for r := range next {
+				rng := rand.New(rand.NewSource(resampleSeed(opts.Seed, r)))
+				for i := range draws {
+					draws[i] = 0
+				}
+				for i := int64(0); i < acc.count; i++ {
+					k := rng.Int63n(acc.count)
+					draws[sort.Search(len(cumulative), func(j int) bool { return cumulative[j] > k })]++
+				}
+				// Resamples are only ever approximate, so float
+				// accumulators do, whatever the kind of the data.
+				resample, _ := New(Options{Kind: Float})
+				for i, count := range draws {
+					if count != 0 {
+						resample.addN(floatNumber(values[i].Float), count)
+					}
+				}
+				for i, metric := range metrics {
+					estimates[i][r] = metric.Compute(resample).Value
+				}
+			}
+		}()
+	}
+	for r := 0; r < opts.Resamples; r++ {
+		next <- r
+	}
+	close(next)
+	wg.Wait()
+	intervals := make([]Interval, 0, len(metrics))
+	for i, metric := range metrics {
+		sort.Float64s(estimates[i])
+		intervals = append(intervals, Interval{
+			Name:   metric.Name(),
+			Label:  metric.Label(),
+			Method: "bootstrap",
+			Level:  level,
+			Low:    sortedQuantile(estimates[i], (1-level)/2),
+			High:   sortedQuantile(estimates[i], (1+level)/2),
+		})
+	}
+	return intervals, nil
+}
+
+// resampleSeed spreads the resample index over the seed with SplitMix64, so
+// neighbouring seeds do not share resamples.
+func resampleSeed(seed int64, r int) int64 {
+	z := uint64(seed) + uint64(r+1)*0x9E3779B97F4A7C15
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	return int64(z ^ z>>31)
+}
+
+// sortedQuantile interpolates the p-quantile of sorted values the way
+// Quantile does.
+func sortedQuantile(values []float64, p float64) float64 {
+	h := float64(len(values)-1) * p
+	lower := int(math.Floor(h))
+	if lower+1 >= len(values) {
+		return values[lower]
+	}
+	return values[lower] + (h-float64(lower))*(values[lower+1]-values[lower])
+}
@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// Metric is a single statistic that can be evaluated on an Accumulator.
+// Name is the registry key (and the CLI flag), Label is what gets printed.
+type Metric interface {
+	Name() string
+	Label() string
+	Compute(acc *Accumulator) Result
+}
+
+// Result is the value of one metric. Exact is set when the metric could be
+// computed without rounding, which only happens for decimal data. Discrete
+// is set for metrics whose value is one of the input values, like the mode,
+// rather than a derived real number.
+type Result struct {
+	Name     string
+	Label    string
+	Value    float64
+	Exact    *big.Rat
+	Discrete bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Metric)
+	order      []string
+	defaults   []string
+)
+
+// Register makes a metric available by name. Metrics are reported in the
+// order they were registered. It panics if the name is already taken.
+func Register(metric Metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[metric.Name()]; ok {
+		panic("stats: Register called twice for metric " + metric.Name())
+	}
+	registry[metric.Name()] = metric
+	order = append(order, metric.Name())
+}
+
+// RegisterDefault registers a metric that is also computed when no metric
+// is asked for explicitly.
+func RegisterDefault(metric Metric) {
+	Register(metric)
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	defaults = append(defaults, metric.Name())
+}
+
+// Metrics returns every registered metric in registration order.
+func Metrics() []Metric {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	metrics := make([]Metric, 0, len(order))
+	for _, name := range order {
+		metrics = append(metrics, registry[name])
+	}
+	return metrics
+}
+
+// Defaults returns the metrics registered with RegisterDefault.
+func Defaults() []Metric {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	metrics := make([]Metric, 0, len(defaults))
+	for _, name := range defaults {
+		metrics = append(metrics, registry[name])
+	}
+	return metrics
+}
+
+// Lookup resolves metric names through the registry.
+func Lookup(names ...string) ([]Metric, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	metrics := make([]Metric, 0, len(names))
+	for _, name := range names {
+		metric, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric %q", name)
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// Compute feeds data into a fresh accumulator and evaluates metrics on it.
+// With no metrics given the default ones are computed.
+func Compute(data []int32, metrics ...Metric) ([]Result, error) {
+	acc := NewAccumulator()
+	for _, num := range data {
+		if err := acc.Add(num); err != nil {
+			return nil, err
+		}
+	}
+	return acc.Compute(metrics...)
+}
+
+func (acc *Accumulator) Compute(metrics ...Metric) ([]Result, error) {
+	if acc.count == 0 {
+		return nil, ErrEmpty
+	}
+	if len(metrics) == 0 {
+		metrics = Defaults()
+	}
+	results := make([]Result, 0, len(metrics))
+	for _, metric := range metrics {
+		results = append(results, metric.Compute(acc))
+	}
+	return results, nil
+}
+
+type funcMetric struct {
+	name     string
+	label    string
+	discrete bool
+	fn       func(*Accumulator) Number
+}
+
+func (m funcMetric) Name() string  { return m.name }
+func (m funcMetric) Label() string { return m.label }
+
+func (m funcMetric) Compute(acc *Accumulator) Result {
+	value := m.fn(acc)
+	return Result{Name: m.name, Label: m.label, Value: value.Float, Exact: value.Exact, Discrete: m.discrete}
+}
+
+// NewMetric wraps a plain function into a Metric.
+func NewMetric(name, label string, fn func(*Accumulator) Number) Metric {
+	return funcMetric{name: name, label: label, fn: fn}
+}
+
+// NewDiscreteMetric is like NewMetric for metrics whose value is an input value.
+func NewDiscreteMetric(name, label string, fn func(*Accumulator) Number) Metric {
+	return funcMetric{name: name, label: label, discrete: true, fn: fn}
+}
@@ -0,0 +1,55 @@
+package stats
+
+import "sort"
+
+// Frequency is how many times a value occurs in the data.
+type Frequency struct {
+	Value Number
+	Count uint64
+}
+
+// ModeReport lists every value sharing the highest frequency, in ascending
+// order, so ties are not hidden the way Mode hides them.
+type ModeReport struct {
+	Modes []Number
+	Count uint64
+}
+
+// NoRepeats reports whether every value occurs only once, in which case
+// each of them is technically a mode and the report is not meaningful.
+func (report ModeReport) NoRepeats() bool {
+	return report.Count == 1
+}
+
+func Modes(acc *Accumulator) ModeReport {
+	var report ModeReport
+	acc.counts.each(func(num Number, count uint64) bool {
+		if count < report.Count {
+			return true
+		}
+		if count > report.Count {
+			report.Count = count
+			report.Modes = report.Modes[:0]
+		}
+		report.Modes = append(report.Modes, num)
+		return true
+	})
+	return report
+}
+
+// TopK returns the k most frequent values, by descending count and then by
+// ascending value.
+func TopK(acc *Accumulator, k int) []Frequency {
+	var top []Frequency
+	acc.counts.each(func(num Number, count uint64) bool {
+		top = append(top, Frequency{Value: num, Count: count})
+		return true
+	})
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].Count > top[j].Count
+	})
+	if len(top) > k {
+		top = top[:k]
+	}
+	return top
+}
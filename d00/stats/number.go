@@ -0,0 +1,113 @@
+package stats
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// sqrtPrecision is the mantissa size, in bits, used for square roots of
+// exact values. It is far beyond what any output format prints.
+const sqrtPrecision = 256
+
+// Number is an input value or a metric value. Float is always set; Exact
+// holds the same number without rounding when the data is decimal and the
+// value can be represented as a fraction. Exact is shared, never modify it.
+type Number struct {
+	Float float64
+	Exact *big.Rat
+}
+
+func floatNumber(f float64) Number {
+	return Number{Float: f}
+}
+
+func ratNumber(r *big.Rat) Number {
+	f, _ := r.Float64()
+	return Number{Float: f, Exact: r}
+}
+
+// String prints the number the way it was read: exact values as a
+// terminating decimal when they have one, floats in the shortest form that
+// parses back to the same value.
+func (n Number) String() string {
+	if n.Exact == nil {
+		return strconv.FormatFloat(n.Float, 'f', -1, 64)
+	}
+	if n.Exact.IsInt() {
+		return n.Exact.Num().String()
+	}
+	if digits, ok := decimalDigits(n.Exact); ok {
+		return n.Exact.FloatString(digits)
+	}
+	return n.Exact.RatString()
+}
+
+// decimalDigits returns how many decimals r needs to be written exactly, or
+// false if its denominator has prime factors other than 2 and 5.
+func decimalDigits(r *big.Rat) (int, bool) {
+	denom := new(big.Int).Set(r.Denom())
+	two, five := 0, 0
+	for _, p := range []struct {
+		factor *big.Int
+		count  *int
+	}{{big.NewInt(2), &two}, {big.NewInt(5), &five}} {
+		mod := new(big.Int)
+		for {
+			quo, rem := new(big.Int).QuoRem(denom, p.factor, mod)
+			if rem.Sign() != 0 {
+				break
+			}
+			denom = quo
+			*p.count++
+		}
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if two > five {
+		return two, true
+	}
+	return five, true
+}
+
+func addNumbers(a, b Number) Number {
+	if a.Exact != nil && b.Exact != nil {
+		return ratNumber(new(big.Rat).Add(a.Exact, b.Exact))
+	}
+	return floatNumber(a.Float + b.Float)
+}
+
+func subNumbers(a, b Number) Number {
+	if a.Exact != nil && b.Exact != nil {
+		return ratNumber(new(big.Rat).Sub(a.Exact, b.Exact))
+	}
+	return floatNumber(a.Float - b.Float)
+}
+
+func midpoint(a, b Number) Number {
+	if a.Exact != nil && b.Exact != nil {
+		sum := new(big.Rat).Add(a.Exact, b.Exact)
+		return ratNumber(sum.Quo(sum, big.NewRat(2, 1)))
+	}
+	return floatNumber((a.Float + b.Float) / 2)
+}
+
+// quoNumbers divides exactly when it can and falls back to float division,
+// which yields NaN or Inf instead of panicking on a zero divisor.
+func quoNumbers(a, b Number) Number {
+	if a.Exact != nil && b.Exact != nil && b.Exact.Sign() != 0 {
+		return ratNumber(new(big.Rat).Quo(a.Exact, b.Exact))
+	}
+	return floatNumber(a.Float / b.Float)
+}
+
+func sqrtNumber(n Number) Number {
+	if n.Exact == nil || n.Exact.Sign() < 0 {
+		return floatNumber(math.Sqrt(n.Float))
+	}
+	root := new(big.Float).SetPrec(sqrtPrecision).SetRat(n.Exact)
+	root.Sqrt(root)
+	exact, _ := root.Rat(nil)
+	return ratNumber(exact)
+}
@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// madScale turns the MAD into a consistent estimate of the SD of normal
+// data.
+const madScale = 1.4826
+
+// Outlier rules and their usual thresholds.
+var outlierThresholds = map[string]float64{
+	"zscore": 3,
+	"iqr":    1.5,
+	"mad":    3.5,
+}
+
+// IsOutlierRule reports whether NewFence knows the rule.
+func IsOutlierRule(method string) bool {
+	_, ok := outlierThresholds[method]
+	return ok
+}
+
+// Fence is the range of values an outlier rule accepts, inclusive. Method
+// and Threshold are the rule and the k it was built with.
+type Fence struct {
+	Method    string
+	Threshold float64
+	Low       float64
+	High      float64
+}
+
+// NewFence builds the fence of an outlier rule over the data:
+//
+//	zscore: mean ± k·SD
+//	iqr:    [Q1 - k·IQR : Q3 + k·IQR]
+//	mad:    median ± k·1.4826·MAD
+//
+// A zero threshold picks the rule's usual one: 3, 1.5 and 3.5.
+func NewFence(acc *Accumulator, method string, threshold float64) (Fence, error) {
+	usual, ok := outlierThresholds[method]
+	if !ok {
+		return Fence{}, fmt.Errorf("unknown outlier rule %q", method)
+	}
+	if acc.count == 0 {
+		return Fence{}, ErrEmpty
+	}
+	if threshold == 0 {
+		threshold = usual
+	}
+	if threshold < 0 || math.IsNaN(threshold) {
+		return Fence{}, fmt.Errorf("outlier threshold must be positive, got %v", threshold)
+	}
+	fence := Fence{Method: method, Threshold: threshold}
+	switch method {
+	case "zscore":
+		mean, sd := Mean(acc).Float, SD(acc).Float
+		fence.Low, fence.High = mean-threshold*sd, mean+threshold*sd
+	case "iqr":
+		q1, q3 := Quantile(acc, 0.25).Float, Quantile(acc, 0.75).Float
+		fence.Low, fence.High = q1-threshold*(q3-q1), q3+threshold*(q3-q1)
+	case "mad":
+		median, mad := Median(acc).Float, MAD(acc).Float
+		fence.Low, fence.High = median-threshold*madScale*mad, median+threshold*madScale*mad
+	}
+	return fence, nil
+}
+
+// Contains reports whether num is not an outlier.
+func (fence Fence) Contains(num Number) bool {
+	return num.Float >= fence.Low && num.Float <= fence.High
+}
@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// PairAccumulator follows two columns read row by row. Covariance and
+// Pearson correlation are kept with Welford's co-moment update; Spearman
+// correlation needs the ranks of every value, so the pairs themselves are
+// kept as well.
+type PairAccumulator struct {
+	count int64
+	meanX float64
+	meanY float64
+	m2X   float64
+	m2Y   float64
+	coM2  float64
+	xs    []float64
+	ys    []float64
+}
+
+func NewPairAccumulator() *PairAccumulator {
+	return &PairAccumulator{}
+}
+
+func (pair *PairAccumulator) Add(x, y float64) {
+	pair.count++
+	n := float64(pair.count)
+	deltaX := x - pair.meanX
+	pair.meanX += deltaX / n
+	deltaY := y - pair.meanY
+	pair.meanY += deltaY / n
+	pair.m2X += deltaX * (x - pair.meanX)
+	pair.m2Y += deltaY * (y - pair.meanY)
+	pair.coM2 += deltaX * (y - pair.meanY)
+	pair.xs = append(pair.xs, x)
+	pair.ys = append(pair.ys, y)
+}
+
+func (pair *PairAccumulator) Count() int64 {
+	return pair.count
+}
+
+// Covariance is the population covariance, matching SD and Variance.
+func Covariance(pair *PairAccumulator) float64 {
+	return pair.coM2 / float64(pair.count)
+}
+
+// Pearson is the linear correlation coefficient, NaN when either column is
+// constant.
+func Pearson(pair *PairAccumulator) float64 {
+	return pair.coM2 / math.Sqrt(pair.m2X*pair.m2Y)
+}
+
+// Spearman is the Pearson correlation of the ranks, with tied values
+// sharing their average rank.
+func Spearman(pair *PairAccumulator) float64 {
+	ranked := NewPairAccumulator()
+	rankX, rankY := ranks(pair.xs), ranks(pair.ys)
+	for i := range rankX {
+		ranked.Add(rankX[i], rankY[i])
+	}
+	return Pearson(ranked)
+}
+
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+	result := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end)/2 + 1
+		for i := start; i <= end; i++ {
+			result[order[i]] = rank
+		}
+		start = end + 1
+	}
+	return result
+}
@@ -0,0 +1,265 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// Window keeps the metrics of the last size values of a stream. Every value
+// added updates them in place instead of recomputing the window: the values are
+// kept sorted by binary insertion for the median, counted per value and
+// grouped by count for the mode, and summed exactly for the mean and SD of
+// int and decimal data. Float data has no exact sum, so its mean and SD are
+// recomputed from the sorted values, which costs no more than keeping them
+// sorted: running sums of floats would never recover the precision a large
+// value takes with it when it leaves the window.
+type Window struct {
+	parser
+	size int
+	ring []Number
+	next int
+	// sorted holds the window in ascending order.
+	sorted []Number
+	// counts maps a value's key to how often it occurs in the window, and
+	// byCount lists the values occurring exactly c times in ascending
+	// order, so the mode is byCount[maxCount][0].
+	counts   map[any]int
+	byCount  map[int][]Number
+	maxCount int
+	// sum and sumSquares are the exact sums of x and x², nil for float
+	// data.
+	sum        *big.Rat
+	sumSquares *big.Rat
+}
+
+func NewWindow(size int, opts Options) (*Window, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("window size must be positive, got %d", size)
+	}
+	p, err := newParser(opts)
+	if err != nil {
+		return nil, err
+	}
+	w := &Window{
+		parser:  p,
+		size:    size,
+		ring:    make([]Number, 0, size),
+		sorted:  make([]Number, 0, size),
+		counts:  make(map[any]int),
+		byCount: make(map[int][]Number),
+	}
+	if opts.Kind != Float {
+		w.sum, w.sumSquares = new(big.Rat), new(big.Rat)
+	}
+	return w, nil
+}
+
+// Len is the number of values in the window, its size once it has filled
+// up.
+func (w *Window) Len() int {
+	return len(w.ring)
+}
+
+func (w *Window) AddString(s string) error {
+	num, err := w.Parse(s)
+	if err != nil {
+		return err
+	}
+	w.add(num)
+	return nil
+}
+
+func (w *Window) add(num Number) {
+	if len(w.ring) == w.size {
+		w.remove(w.ring[w.next])
+		w.ring[w.next] = num
+		w.next = (w.next + 1) % w.size
+	} else {
+		w.ring = append(w.ring, num)
+	}
+	w.sorted = insertSorted(w.sorted, num)
+
+	key := numberKey(num)
+	count := w.counts[key]
+	if count > 0 {
+		w.byCount[count] = removeSorted(w.byCount[count], num)
+	}
+	count++
+	w.counts[key] = count
+	w.byCount[count] = insertSorted(w.byCount[count], num)
+	if count > w.maxCount {
+		w.maxCount = count
+	}
+
+	if w.sum != nil {
+		x := exactValue(num)
+		w.sum.Add(w.sum, x)
+		w.sumSquares.Add(w.sumSquares, new(big.Rat).Mul(x, x))
+	}
+}
+
+// remove takes the oldest value out before the ring slot is reused.
+func (w *Window) remove(num Number) {
+	w.sorted = removeSorted(w.sorted, num)
+
+	key := numberKey(num)
+	count := w.counts[key]
+	w.byCount[count] = removeSorted(w.byCount[count], num)
+	if count == w.maxCount && len(w.byCount[count]) == 0 {
+		w.maxCount--
+	}
+	count--
+	if count == 0 {
+		delete(w.counts, key)
+	} else {
+		w.counts[key] = count
+		w.byCount[count] = insertSorted(w.byCount[count], num)
+	}
+
+	if w.sum != nil {
+		x := exactValue(num)
+		w.sum.Sub(w.sum, x)
+		w.sumSquares.Sub(w.sumSquares, new(big.Rat).Mul(x, x))
+	}
+}
+
+// exactValue is num as a fraction. Int values are whole floats, which
+// convert exactly.
+func exactValue(num Number) *big.Rat {
+	if num.Exact != nil {
+		return num.Exact
+	}
+	return new(big.Rat).SetFloat64(num.Float)
+}
+
+// result rounds an exact metric to a float for int data, which prints its
+// derived values as floats.
+func (w *Window) result(exact *big.Rat) Number {
+	if w.opts.Kind == Int {
+		f, _ := exact.Float64()
+		return floatNumber(f)
+	}
+	return ratNumber(exact)
+}
+
+// floatMoments runs Welford's method over the sorted window of float
+// data, and returns the mean and the sum of squared deviations.
+func (w *Window) floatMoments() (mean, m2 float64) {
+	for i, num := range w.sorted {
+		delta := num.Float - mean
+		mean += delta / float64(i+1)
+		m2 += delta * (num.Float - mean)
+	}
+	return mean, m2
+}
+
+func (w *Window) Mean() Number {
+	if w.sum != nil {
+		return w.result(new(big.Rat).Quo(w.sum, big.NewRat(int64(len(w.ring)), 1)))
+	}
+	mean, _ := w.floatMoments()
+	return floatNumber(mean)
+}
+
+func (w *Window) Median() Number {
+	n := len(w.sorted)
+	if n%2 == 0 {
+		return midpoint(w.sorted[n/2], w.sorted[n/2-1])
+	}
+	return w.sorted[n/2]
+}
+
+// Mode is the most frequent value in the window, the smallest one on ties.
+func (w *Window) Mode() Number {
+	return w.byCount[w.maxCount][0]
+}
+
+// SD is the population standard deviation of the window.
+func (w *Window) SD() Number {
+	if w.sum != nil {
+		n := big.NewRat(int64(len(w.ring)), 1)
+		variance := new(big.Rat).Mul(w.sum, w.sum)
+		variance.Quo(variance, n)
+		variance.Sub(w.sumSquares, variance)
+		sd := sqrtNumber(ratNumber(variance.Quo(variance, n)))
+		return w.result(sd.Exact)
+	}
+	_, m2 := w.floatMoments()
+	return floatNumber(math.Sqrt(m2 / float64(len(w.ring))))
+}
+
+var windowMetrics = map[string]func(*Window) Number{
+	"mean":   (*Window).Mean,
+	"median": (*Window).Median,
+	"mode":   (*Window).Mode,
+	"sd":     (*Window).SD,
+}
+
+// Compute evaluates the metrics a window can maintain: mean, median, mode
+// and SD.
+func (w *Window) Compute(metrics ...Metric) ([]Result, error) {
+	if len(w.ring) == 0 {
+		return nil, ErrEmpty
+	}
+	if len(metrics) == 0 {
+		metrics = Defaults()
+	}
+	results := make([]Result, 0, len(metrics))
+	for _, metric := range metrics {
+		fn, ok := windowMetrics[metric.Name()]
+		if !ok {
+			return nil, fmt.Errorf("metric %q is not available over a window", metric.Name())
+		}
+		value := fn(w)
+		results = append(results, Result{
+			Name:     metric.Name(),
+			Label:    metric.Label(),
+			Value:    value.Float,
+			Exact:    value.Exact,
+			Discrete: metric.Name() == "mode",
+		})
+	}
+	return results, nil
+}
+
+// WindowMetric reports whether a metric can be computed over a Window.
+func WindowMetric(metric Metric) bool {
+	_, ok := windowMetrics[metric.Name()]
+	return ok
+}
+
+func compareNumbers(a, b Number) int {
+	if a.Exact != nil && b.Exact != nil {
+		return a.Exact.Cmp(b.Exact)
+	}
+	switch {
+	case a.Float < b.Float:
+		return -1
+	case a.Float > b.Float:
+		return 1
+	}
+	return 0
+}
+
+// numberKey identifies equal values in a map.
+func numberKey(num Number) any {
+	if num.Exact != nil {
+		return num.Exact.RatString()
+	}
+	return num.Float
+}
+
+func insertSorted(values []Number, num Number) []Number {
+	i := sort.Search(len(values), func(i int) bool { return compareNumbers(values[i], num) >= 0 })
+	values = append(values, Number{})
+	copy(values[i+1:], values[i:])
+	values[i] = num
+	return values
+}
+
+func removeSorted(values []Number, num Number) []Number {
+	i := sort.Search(len(values), func(i int) bool { return compareNumbers(values[i], num) >= 0 })
+	return append(values[:i], values[i+1:]...)
+}
@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newWindow(t *testing.T, size int, opts Options, values ...string) *Window {
+	t.Helper()
+	w, err := NewWindow(size, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range values {
+		if err := w.AddString(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+// TestWindowForgetsLargeValues checks that a value leaving the window takes
+// nothing with it: the metrics are those of the values still in it.
+func TestWindowForgetsLargeValues(t *testing.T) {
+	unbounded := func(kind Kind) Options { return Options{Kind: kind} }
+	tests := []struct {
+		name     string
+		opts     Options
+		size     int
+		values   []string
+		mean, sd float64
+	}{
+		{"float after 1e17", unbounded(Float), 2, []string{"1e17", "1", "2", "3"}, 2.5, 0.5},
+		{"float after 1e300", unbounded(Float), 3, []string{"1e300", "-1e300", "1", "2", "4"}, 7.0 / 3, math.Sqrt(14.0 / 9)},
+		{"int after 2^53", unbounded(Int), 2, []string{"9007199254740992", "1", "2"}, 1.5, 0.5},
+		{"decimal after 1e300", unbounded(Decimal), 2, []string{"1e300", "1", "2"}, 1.5, 0.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newWindow(t, test.size, test.opts, test.values...)
+			if mean := w.Mean().Float; mean != test.mean {
+				t.Errorf("mean %v, want %v", mean, test.mean)
+			}
+			if sd := w.SD().Float; sd != test.sd {
+				t.Errorf("SD %v, want %v", sd, test.sd)
+			}
+		})
+	}
+}
+
+// TestWindowDoesNotDrift feeds a long stream and checks the window against
+// a fresh one holding only its last values.
+func TestWindowDoesNotDrift(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for name, opts := range map[string]Options{"int": DefaultOptions(), "float": {Kind: Float}} {
+		t.Run(name, func(t *testing.T) {
+			long := newWindow(t, 3, opts)
+			for i := 0; i < 100000; i++ {
+				value := fmt.Sprint(rng.Intn(2*MaxValue+1) - MaxValue)
+				if opts.Kind == Float {
+					value = fmt.Sprint(rng.NormFloat64() * math.Pow(10, float64(rng.Intn(40)-20)))
+				}
+				if err := long.AddString(value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			last := []string{"1", "2", "4"}
+			for _, value := range last {
+				if err := long.AddString(value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fresh := newWindow(t, 3, opts, last...)
+			if long.Mean() != fresh.Mean() || long.SD() != fresh.SD() {
+				t.Errorf("mean %v and SD %v, want %v and %v", long.Mean(), long.SD(), fresh.Mean(), fresh.SD())
+			}
+			if sd := long.SD().Float; sd != math.Sqrt(14.0/9) {
+				t.Errorf("SD %v, want %v", sd, math.Sqrt(14.0/9))
+			}
+		})
+	}
+}
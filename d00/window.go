@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"d00/stats"
+)
+
+// windowConfig is set by -window.
+type windowConfig struct {
+	size  int
+	every int
+}
+
+// runWindow prints the metrics of the last cfg.window.size values after
+// every cfg.window.every accepted values, as soon as they are known, so d00
+// can sit at the end of a tail -f pipe.
+func runWindow(input io.Reader, cfg *config) (readSummary, error) {
+	var summary readSummary
+	window, err := stats.NewWindow(cfg.window.size, cfg.options)
+	if err != nil {
+		return summary, err
+	}
+	emit := windowPrinter(cfg.format)
+	scanner := bufio.NewScanner(input)
+	for line := 1; scanner.Scan(); line++ {
+		if err := window.AddString(scanner.Text()); err != nil {
+			if err := summary.reject(cfg, &lineError{line: line, err: err}); err != nil {
+				return summary, err
+			}
+			continue
+		}
+		summary.accepted++
+		if summary.accepted%cfg.window.every != 0 {
+			continue
+		}
+		results, err := window.Compute(cfg.metrics...)
+		if err != nil {
+			return summary, err
+		}
+		emit(line, window.Len(), results)
+	}
+	return summary, scanner.Err()
+}
+
+// windowPrinter returns a function printing one window. Text gets a header
+// per window, json one object per line, csv a single header and one row per
+// window, and yaml one document per window.
+func windowPrinter(format string) func(line, size int, results []stats.Result) {
+	switch format {
+	case "json", "yaml":
+		return func(line, size int, results []stats.Result) {
+			doc := object{{"line", intScalar(line)}, {"size", intScalar(size)}}
+			doc = append(doc, report{results: results}.object()...)
+			if format == "json" {
+				var b strings.Builder
+				writeJSON(&b, doc)
+				fmt.Println(b.String())
+				return
+			}
+			fmt.Println("---")
+			printYAML(os.Stdout, doc)
+		}
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		header := false
+		return func(line, size int, results []stats.Result) {
+			cells := flattenCSV("", append(object{{"line", intScalar(line)}, {"size", intScalar(size)}},
+				report{results: results}.object()...))
+			row := make([]string, 0, len(cells))
+			if !header {
+				for _, cell := range cells {
+					row = append(row, cell.key)
+				}
+				writer.Write(row)
+				row = row[:0]
+				header = true
+			}
+			for _, cell := range cells {
+				row = append(row, cell.value.(scalar).text)
+			}
+			writer.Write(row)
+			writer.Flush()
+		}
+	}
+	return func(line, size int, results []stats.Result) {
+		fmt.Printf("[line %d, last %d]\n", line, size)
+		report{results: results}.printText()
+	}
+}
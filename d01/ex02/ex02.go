@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"recipes"
+)
+
+func readChanges(fileName string) (recipes.Changes, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return recipes.ReadChanges(file)
+}
+
+func main() {
+	flagDB := flag.String("db", "", "./patchDB --db original_database.xml --patch changes.patch")
+	flagPatch := flag.String("patch", "", "./patchDB --db original_database.xml --patch changes.patch (a -format patch or json diff of compareDB)")
+	flagReverse := flag.Bool("reverse", false, "./patchDB --db stolen_database.json --patch changes.patch -reverse")
+	flagTo := flag.String("to", "", "./patchDB --db original_database.xml --patch changes.patch -to json")
+	flag.Parse()
+	if *flagDB == "" || *flagPatch == "" || flag.NArg() != 0 {
+		flag.PrintDefaults()
+		log.Fatal("Wrong usage")
+	}
+	data, codec, err := recipes.ReadFile(*flagDB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	changes, err := readChanges(*flagPatch)
+	if err != nil {
+		log.Fatalf("%s: %v", *flagPatch, err)
+	}
+	if *flagReverse {
+		changes = changes.Reverse()
+	}
+	cakes, err := recipes.Apply(data.Cake, changes)
+	if err != nil {
+		log.Fatalf("%s does not apply to %s:\n%v", *flagPatch, *flagDB, err)
+	}
+	if *flagTo != "" {
+		if codec, err = recipes.Lookup(*flagTo); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := codec.Write(os.Stdout, cakes); err != nil {
+		log.Fatal(err)
+	}
+}
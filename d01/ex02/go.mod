@@ -0,0 +1,3 @@
+module patchDB
+
+go 1.18
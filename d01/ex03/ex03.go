@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"recipes"
+)
+
+func writeReport(fileName string, conflicts recipes.MergeConflicts) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := conflicts.WriteJSON(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func main() {
+	flagBase := flag.String("base", "", "./mergeDB --base original_database.xml --ours ours.json --theirs theirs.yaml")
+	flagOurs := flag.String("ours", "", "./mergeDB --base original_database.xml --ours ours.json --theirs theirs.yaml")
+	flagTheirs := flag.String("theirs", "", "./mergeDB --base original_database.xml --ours ours.json --theirs theirs.yaml")
+	flagTo := flag.String("to", "", "./mergeDB ... -to json (the format of --ours by default)")
+	flagReport := flag.String("report", "", "./mergeDB ... -report conflicts.json (a JSON conflict report instead of text on stderr)")
+	flag.Parse()
+	if *flagBase == "" || *flagOurs == "" || *flagTheirs == "" || flag.NArg() != 0 {
+		flag.PrintDefaults()
+		log.Fatal("Wrong usage")
+	}
+	base, _, err := recipes.ReadFile(*flagBase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ours, codec, err := recipes.ReadFile(*flagOurs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	theirs, _, err := recipes.ReadFile(*flagTheirs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *flagTo != "" {
+		if codec, err = recipes.Lookup(*flagTo); err != nil {
+			log.Fatal(err)
+		}
+	}
+	merged, conflicts := recipes.Merge(base.Cake, ours.Cake, theirs.Cake)
+	if err := codec.Write(os.Stdout, merged); err != nil {
+		log.Fatal(err)
+	}
+	if *flagReport != "" {
+		err = writeReport(*flagReport, conflicts)
+	} else {
+		err = conflicts.WriteText(os.Stderr)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		os.Exit(1)
+	}
+}
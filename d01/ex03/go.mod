@@ -0,0 +1,3 @@
+module mergeDB
+
+go 1.18
@@ -0,0 +1,153 @@
+package recipes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Reverse returns the changes that undo changes.
+func (changes Changes) Reverse() Changes {
+	reversed := make(Changes, len(changes))
+	for i, change := range changes {
+		change.Old, change.New = change.New, change.Old
+		switch change.Kind {
+		case Added:
+			change.Kind = Removed
+		case Removed:
+			change.Kind = Added
+		}
+		reversed[i] = change
+	}
+	return reversed
+}
+
+// Conflict is a change that does not fit the database it is applied to.
+type Conflict struct {
+	Change Change
+	Reason string
+}
+
+func (conflict Conflict) Error() string {
+	return fmt.Sprintf("%s %s: %s", conflict.Change.Kind, conflict.Change.where(), conflict.Reason)
+}
+
+// Conflicts is the error of Apply: every change that did not fit, in order.
+type Conflicts []Conflict
+
+func (conflicts Conflicts) Error() string {
+	lines := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		lines[i] = conflict.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// where names the value a change is about.
+func (change Change) where() string {
+	if change.Ingredient == "" {
+		return fmt.Sprintf("cake %s %s", strconv.Quote(change.Cake), change.Field)
+	}
+	return fmt.Sprintf("cake %s ingredient %s %s", strconv.Quote(change.Cake), strconv.Quote(change.Ingredient), change.Field)
+}
+
+// Apply returns cakes with changes made to a copy of them. A change only
+// applies to the value it was made from: an added value must not be there
+// yet, and a changed or removed one must still be what it was. Anything
+// else is a Conflict, and if there is any Apply returns all of them and no
+// database, rather than overwrite what changed since the diff.
+func Apply(cakes map[string]Cake, changes Changes) (map[string]Cake, error) {
+	patched := make(map[string]Cake, len(cakes))
+	for name, cake := range cakes {
+		patched[name] = copyCake(cake)
+	}
+	// A cake or an ingredient comes before what it holds, so removing one
+	// waits until what it holds is gone, in reverse order.
+	reasons := make([]string, len(changes))
+	var removals []int
+	for i, change := range changes {
+		if change.Kind == Removed && change.whole() {
+			removals = append(removals, i)
+			continue
+		}
+		reasons[i] = applyChange(patched, change)
+	}
+	for i := len(removals) - 1; i >= 0; i-- {
+		reasons[removals[i]] = applyChange(patched, changes[removals[i]])
+	}
+	var conflicts Conflicts
+	for i, reason := range reasons {
+		if reason != "" {
+			conflicts = append(conflicts, Conflict{Change: changes[i], Reason: reason})
+		}
+	}
+	if len(conflicts) != 0 {
+		return nil, conflicts
+	}
+	return patched, nil
+}
+
+// applyChange makes one change to cakes, or says why it does not fit.
+func applyChange(cakes map[string]Cake, change Change) string {
+	cake, ok := cakes[change.Cake]
+	if change.Field == FieldTime {
+		if reason := checkValue(change, cake.Time, ok); reason != "" {
+			return reason
+		}
+		switch change.Kind {
+		case Added:
+			cakes[change.Cake] = Cake{Time: change.New, IngredientMap: make(map[string]Ingredient)}
+		case Removed:
+			if len(cake.IngredientMap) != 0 {
+				return fmt.Sprintf("the cake still has %d ingredients", len(cake.IngredientMap))
+			}
+			delete(cakes, change.Cake)
+		default:
+			cake.Time = change.New
+			cakes[change.Cake] = cake
+		}
+		return ""
+	}
+	if !ok {
+		return "no such cake"
+	}
+	ingredient, ok := cake.IngredientMap[change.Ingredient]
+	if change.Field == FieldCount {
+		if reason := checkValue(change, ingredient.IngredientCount, ok); reason != "" {
+			return reason
+		}
+		switch change.Kind {
+		case Removed:
+			if ingredient.IngredientUnit != "" {
+				return fmt.Sprintf("the ingredient still has unit %s", strconv.Quote(ingredient.IngredientUnit))
+			}
+			delete(cake.IngredientMap, change.Ingredient)
+		default:
+			cake.IngredientMap[change.Ingredient] = Ingredient{IngredientCount: change.New, IngredientUnit: ingredient.IngredientUnit}
+		}
+		return ""
+	}
+	if !ok {
+		return "no such ingredient"
+	}
+	if reason := checkValue(change, ingredient.IngredientUnit, ingredient.IngredientUnit != ""); reason != "" {
+		return reason
+	}
+	ingredient.IngredientUnit = change.New
+	cake.IngredientMap[change.Ingredient] = ingredient
+	return ""
+}
+
+// checkValue says why change does not fit the value it is about, given as
+// it is in the database and whether it is set there at all.
+func checkValue(change Change, have string, set bool) string {
+	switch {
+	case change.Kind == Added && set:
+		return fmt.Sprintf("already there as %s", strconv.Quote(have))
+	case change.Kind != Added && !set:
+		return fmt.Sprintf("not there, expected %s", strconv.Quote(change.Old))
+	case change.Kind != Added && have != change.Old:
+		return fmt.Sprintf("is %s, expected %s", strconv.Quote(have), strconv.Quote(change.Old))
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package recipes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyThenReverse(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+	}{
+		{"nothing", "A,5 min,Egg,2,\n", "A,5 min,Egg,2,\n"},
+		{"from empty", "", "A,5 min,Egg,2,pcs\nB,1 min,,,\n"},
+		{"to empty", "A,5 min,Egg,2,pcs\nB,1 min,,,\n", ""},
+		{"values", "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\nA,5 min,Oil,1,tbsp\n",
+			"A,6 min,Egg,3,pcs\nA,6 min,Milk,1,\nA,6 min,Oil,1,tsp\nA,6 min,Salt,1,pinch\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			old, new := csvCakes(t, test.old), csvCakes(t, test.new)
+			changes := Diff(old, new)
+			patched, err := Apply(old, changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := Diff(patched, new); len(diff) != 0 {
+				t.Errorf("applied, differs from new by %+v", diff)
+			}
+			reverted, err := Apply(patched, changes.Reverse())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(reverted, old) {
+				t.Errorf("reverted to %+v, want %+v", reverted, old)
+			}
+		})
+	}
+}
+
+func TestApplyLeavesItsInputAlone(t *testing.T) {
+	old := csvCakes(t, "A,5 min,Egg,2,\n")
+	if _, err := Apply(old, Diff(old, csvCakes(t, "A,5 min,Egg,3,\n"))); err != nil {
+		t.Fatal(err)
+	}
+	if count := old["A"].IngredientMap["Egg"].IngredientCount; count != "2" {
+		t.Errorf("Apply changed its input to count %q", count)
+	}
+}
+
+func TestApplyConflicts(t *testing.T) {
+	db := "A,5 min,Egg,2,pcs\nA,5 min,Milk,1,\n"
+	tests := []struct {
+		name   string
+		change Change
+		reason string
+	}{
+		{"added cake there", Change{Kind: Added, Cake: "A", Field: FieldTime, New: "6 min"}, `already there as "5 min"`},
+		{"added ingredient there", Change{Kind: Added, Cake: "A", Ingredient: "Egg", Field: FieldCount, New: "3"}, `already there as "2"`},
+		{"added unit there", Change{Kind: Added, Cake: "A", Ingredient: "Egg", Field: FieldUnit, New: "g"}, `already there as "pcs"`},
+		{"changed cake missing", Change{Kind: Changed, Cake: "B", Field: FieldTime, Old: "1 min", New: "2 min"}, `not there, expected "1 min"`},
+		{"removed unit missing", Change{Kind: Removed, Cake: "A", Ingredient: "Milk", Field: FieldUnit, Old: "cup"}, `not there, expected "cup"`},
+		{"changed time differs", Change{Kind: Changed, Cake: "A", Field: FieldTime, Old: "4 min", New: "6 min"}, `is "5 min", expected "4 min"`},
+		{"changed count differs", Change{Kind: Changed, Cake: "A", Ingredient: "Egg", Field: FieldCount, Old: "1", New: "3"}, `is "2", expected "1"`},
+		{"removed cake with ingredients", Change{Kind: Removed, Cake: "A", Field: FieldTime, Old: "5 min"}, "the cake still has 2 ingredients"},
+		{"removed ingredient with unit", Change{Kind: Removed, Cake: "A", Ingredient: "Egg", Field: FieldCount, Old: "2"}, `the ingredient still has unit "pcs"`},
+		{"ingredient of no cake", Change{Kind: Added, Cake: "B", Ingredient: "Egg", Field: FieldCount, New: "2"}, "no such cake"},
+		{"unit of no ingredient", Change{Kind: Added, Cake: "A", Ingredient: "Salt", Field: FieldUnit, New: "pinch"}, "no such ingredient"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := Apply(csvCakes(t, db), Changes{test.change})
+			var conflicts Conflicts
+			if !errors.As(err, &conflicts) || len(conflicts) != 1 || patched != nil {
+				t.Fatalf("Apply = %v, %v; want one conflict", patched, err)
+			}
+			if conflicts[0].Reason != test.reason || conflicts[0].Change != test.change {
+				t.Errorf("conflict %+v, want reason %q", conflicts[0], test.reason)
+			}
+		})
+	}
+}
+
+func TestApplyReportsConflictsInOrder(t *testing.T) {
+	changes := Changes{
+		{Kind: Removed, Cake: "A", Field: FieldTime, Old: "9 min"},
+		{Kind: Removed, Cake: "A", Ingredient: "Egg", Field: FieldCount, Old: "2"},
+		{Kind: Changed, Cake: "B", Field: FieldTime, Old: "1 min", New: "2 min"},
+	}
+	_, err := Apply(csvCakes(t, "A,5 min,Egg,2,\n"), changes)
+	var conflicts Conflicts
+	if !errors.As(err, &conflicts) || len(conflicts) != 2 {
+		t.Fatalf("Apply error %v, want two conflicts", err)
+	}
+	if conflicts[0].Change != changes[0] || conflicts[1].Change != changes[2] {
+		t.Errorf("conflicts %+v are out of order", conflicts)
+	}
+}
@@ -0,0 +1,123 @@
+package recipes
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DBReader decodes a recipe database into the canonical model.
+type DBReader interface {
+	Read(r io.Reader) (map[string]Cake, error)
+}
+
+// DBWriter encodes the canonical model as a recipe database.
+type DBWriter interface {
+	Write(w io.Writer, cakes map[string]Cake) error
+}
+
+// Codec is one format of recipe database. Name is the registry key,
+// Extensions the file suffixes it is picked by, with the dot, and Sniff
+// recognises the start of a file when its extension says nothing.
+type Codec interface {
+	Name() string
+	Extensions() []string
+	Sniff(head []byte) bool
+	DBReader
+	DBWriter
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Codec)
+	order      []string
+)
+
+// Register makes a codec available by name. Content is sniffed in the
+// order codecs were registered. It panics if the name is already taken.
+func Register(codec Codec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[codec.Name()]; ok {
+		panic("recipes: Register called twice for codec " + codec.Name())
+	}
+	registry[codec.Name()] = codec
+	order = append(order, codec.Name())
+}
+
+// Codecs returns every registered codec in registration order.
+func Codecs() []Codec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	codecs := make([]Codec, 0, len(order))
+	for _, name := range order {
+		codecs = append(codecs, registry[name])
+	}
+	return codecs
+}
+
+// Lookup returns the codec registered under name.
+func Lookup(name string) (Codec, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	codec, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return codec, nil
+}
+
+// Detect picks the codec for a file by its extension, and otherwise by
+// the first codec that recognises head, the start of its content.
+func Detect(fileName string, head []byte) (Codec, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	codecs := Codecs()
+	for _, codec := range codecs {
+		for _, codecExt := range codec.Extensions() {
+			if ext == codecExt {
+				return codec, nil
+			}
+		}
+	}
+	for _, codec := range codecs {
+		if codec.Sniff(head) {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: unknown file type", fileName)
+}
+
+type funcCodec struct {
+	name       string
+	extensions []string
+	sniff      func(head []byte) bool
+	read       func(r io.Reader) (map[string]Cake, error)
+	write      func(w io.Writer, cakes map[string]Cake) error
+}
+
+func (c funcCodec) Name() string           { return c.name }
+func (c funcCodec) Extensions() []string   { return c.extensions }
+func (c funcCodec) Sniff(head []byte) bool { return c.sniff(head) }
+
+func (c funcCodec) Read(r io.Reader) (map[string]Cake, error) {
+	return c.read(r)
+}
+
+func (c funcCodec) Write(w io.Writer, cakes map[string]Cake) error {
+	return c.write(w, cakes)
+}
+
+// NewCodec wraps plain functions into a Codec.
+func NewCodec(name string, extensions []string, sniff func(head []byte) bool,
+	read func(r io.Reader) (map[string]Cake, error), write func(w io.Writer, cakes map[string]Cake) error) Codec {
+	return funcCodec{name: name, extensions: extensions, sniff: sniff, read: read, write: write}
+}
+
+// startsWith reports whether head starts with prefix once leading white
+// space and a UTF-8 byte order mark are skipped.
+func startsWith(head []byte, prefix string) bool {
+	text := strings.TrimLeft(strings.TrimPrefix(string(head), "\ufeff"), " \t\r\n")
+	return strings.HasPrefix(text, prefix)
+}
@@ -0,0 +1,156 @@
+package recipes
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(NewCodec("csv", []string{".csv"}, sniffCSV, readCSV, writeCSV))
+}
+
+// The columns of the flat CSV layout, one row per cake and ingredient. A
+// cake without ingredients is a single row with the ingredient columns
+// left empty.
+const (
+	csvCake       = "cake"
+	csvTime       = "time"
+	csvIngredient = "ingredient"
+	csvCount      = "count"
+	csvUnit       = "unit"
+)
+
+var csvHeader = []string{csvCake, csvTime, csvIngredient, csvCount, csvUnit}
+
+// csvRequired are the columns a header must have; unit may be left out.
+var csvRequired = []string{csvCake, csvTime, csvIngredient, csvCount}
+
+// sniffCSV recognises the header row: the first line has to name the
+// required columns and nothing else but unit.
+func sniffCSV(head []byte) bool {
+	text := strings.TrimPrefix(string(head), "\ufeff")
+	line := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	columns, err := csvColumns(strings.Split(line, ","))
+	return err == nil && len(columns) != 0
+}
+
+// csvColumns maps the column names of a header, in any order and case, to
+// their index.
+func csvColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := false
+		for _, column := range csvHeader {
+			known = known || name == column
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("column %q given twice", name)
+		}
+		columns[name] = i
+	}
+	for _, column := range csvRequired {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("no %q column", column)
+		}
+	}
+	return columns, nil
+}
+
+// readCSV regroups the rows into cakes. Every error names the row it was
+// found on, counting records rather than lines, so that a cell spanning
+// lines does not shift the count, and the header as row 1.
+func readCSV(r io.Reader) (map[string]Cake, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("row 1: no header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns, err := csvColumns(header)
+	if err != nil {
+		return nil, fmt.Errorf("row 1: %w", err)
+	}
+	cakes := make(map[string]Cake)
+	// cakeRows and ingredientRows remember where a cake or an ingredient was
+	// first seen, for the errors about conflicting rows.
+	cakeRows := make(map[string]int)
+	ingredientRows := make(map[[2]string]int)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return cakes, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("row %d: %d fields, the header has %d", row, len(record), len(header))
+		}
+		field := func(column string) string {
+			i, ok := columns[column]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		name, ingredient := field(csvCake), field(csvIngredient)
+		if name == "" {
+			return nil, fmt.Errorf("row %d: no cake name", row)
+		}
+		cake, seen := cakes[name]
+		if !seen {
+			cake = Cake{Time: field(csvTime), IngredientMap: make(map[string]Ingredient)}
+			cakes[name] = cake
+			cakeRows[name] = row
+		} else if cake.Time != field(csvTime) {
+			return nil, fmt.Errorf("row %d: cake %q takes %q, but %q on row %d",
+				row, name, field(csvTime), cake.Time, cakeRows[name])
+		}
+		if ingredient == "" {
+			if field(csvCount) != "" || field(csvUnit) != "" {
+				return nil, fmt.Errorf("row %d: count or unit without an ingredient for cake %q", row, name)
+			}
+			continue
+		}
+		key := [2]string{name, ingredient}
+		if first, ok := ingredientRows[key]; ok {
+			return nil, fmt.Errorf("row %d: ingredient %q of cake %q already given on row %d", row, ingredient, name, first)
+		}
+		ingredientRows[key] = row
+		cake.IngredientMap[ingredient] = Ingredient{IngredientCount: field(csvCount), IngredientUnit: field(csvUnit)}
+	}
+}
+
+func writeCSV(w io.Writer, cakes map[string]Cake) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, name := range cakeNames(cakes) {
+		cake := cakes[name]
+		if len(cake.IngredientMap) == 0 {
+			if err := writer.Write([]string{name, cake.Time, "", "", ""}); err != nil {
+				return err
+			}
+		}
+		for _, ingredientName := range ingredientNames(cake) {
+			ingredient := cake.IngredientMap[ingredientName]
+			record := []string{name, cake.Time, ingredientName, ingredient.IngredientCount, ingredient.IngredientUnit}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
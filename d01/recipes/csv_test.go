@@ -0,0 +1,53 @@
+package recipes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name, input, err string
+	}{
+		{"no header", "", "row 1: no header"},
+		{"unknown column", "cake,time,ingredient,count,weight\n", `row 1: unknown column "weight"`},
+		{"missing column", "cake,time,ingredient\n", `row 1: no "count" column`},
+		{"short row", "cake,time,ingredient,count\nA,5 min,Egg\n", "row 2: 3 fields, the header has 4"},
+		{"no cake", "cake,time,ingredient,count\n,5 min,Egg,2\n", "row 2: no cake name"},
+		{"conflicting times", "cake,time,ingredient,count\nA,5 min,Egg,2\nB,1 min,,\nA,6 min,Milk,1\n",
+			`row 4: cake "A" takes "6 min", but "5 min" on row 2`},
+		{"multi-line cell", "cake,time,ingredient,count\nA,5 min,\"Egg\nwhite\",2\nB,1 min,,\nA,6 min,Milk,1\n",
+			`row 4: cake "A" takes "6 min", but "5 min" on row 2`},
+		{"repeated ingredient", "cake,time,ingredient,count\nA,5 min,Egg,2\nA,5 min,Egg,3\n",
+			`row 3: ingredient "Egg" of cake "A" already given on row 2`},
+		{"unit without ingredient", "cake,time,ingredient,count,unit\nA,5 min,,,cup\n",
+			`row 2: count or unit without an ingredient for cake "A"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readCSV(strings.NewReader(test.input))
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	cakes := map[string]Cake{
+		"A": {Time: "5 min", IngredientMap: map[string]Ingredient{"Egg": {IngredientCount: "2"}, "Milk": {IngredientCount: "1", IngredientUnit: "cup"}}},
+		"B": {Time: "1 min", IngredientMap: map[string]Ingredient{}},
+	}
+	var b bytes.Buffer
+	if err := writeCSV(&b, cakes); err != nil {
+		t.Fatal(err)
+	}
+	read, err := readCSV(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(cakes) || !sameCake(read["A"], cakes["A"]) || !sameCake(read["B"], cakes["B"]) {
+		t.Errorf("read back %+v, want %+v", read, cakes)
+	}
+}
@@ -0,0 +1,194 @@
+package recipes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ChangeKind says what happened to a field between two databases.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Field is the value of a cake or an ingredient a change is about. A cake
+// exists as long as it has a time and an ingredient as long as it has a
+// count, so a cake or an ingredient being added or removed is its time or
+// count being added or removed.
+type Field string
+
+const (
+	FieldTime  Field = "time"
+	FieldCount Field = "count"
+	FieldUnit  Field = "unit"
+)
+
+// Change is one difference between two databases. Ingredient is empty for
+// the time of a cake, Old is empty for Added and New for Removed.
+type Change struct {
+	Kind       ChangeKind `json:"kind"`
+	Cake       string     `json:"cake"`
+	Ingredient string     `json:"ingredient,omitempty"`
+	Field      Field      `json:"field"`
+	Old        string     `json:"old"`
+	New        string     `json:"new"`
+}
+
+// Changes are ordered by cake and ingredient name, with the time of a cake
+// before its ingredients and the count of an ingredient before its unit.
+type Changes []Change
+
+// Diff returns what changed from old to new. A cake added or removed comes
+// with its ingredients and units, and an ingredient with its unit, so the
+// changes hold all of new that is not in old and the other way round.
+func Diff(old, new map[string]Cake) Changes {
+	return diff(old, new, false)
+}
+
+// SemanticDiff is Diff comparing what ingredients measure rather than how
+// it is written: "3 tsp" and "1 tbsp", or "1/2 cup" and "0.5 cups", are
+// no change, and a count changed in the same unit spelled another way is
+// not a change of unit. Counts that are not numbers compare as text.
+func SemanticDiff(old, new map[string]Cake) Changes {
+	return diff(old, new, true)
+}
+
+func diff(old, new map[string]Cake, semantic bool) Changes {
+	changes := Changes{}
+	for _, name := range unionNames(cakeNames(old), cakeNames(new)) {
+		oldCake, inOld := old[name]
+		newCake, inNew := new[name]
+		changes = changes.add(name, "", FieldTime, oldCake.Time, inOld, newCake.Time, inNew)
+		for _, ingredientName := range unionNames(ingredientNames(oldCake), ingredientNames(newCake)) {
+			oldIngredient, inOld := oldCake.IngredientMap[ingredientName]
+			newIngredient, inNew := newCake.IngredientMap[ingredientName]
+			if semantic && inOld && inNew {
+				oldQuantity, oldErr := oldIngredient.Quantity()
+				newQuantity, newErr := newIngredient.Quantity()
+				if oldErr == nil && newErr == nil {
+					if oldQuantity.Equal(newQuantity) {
+						continue
+					}
+					if oldQuantity.Unit.Name == newQuantity.Unit.Name {
+						changes = changes.add(name, ingredientName, FieldCount,
+							oldIngredient.IngredientCount, true, newIngredient.IngredientCount, true)
+						continue
+					}
+				}
+			}
+			changes = changes.add(name, ingredientName, FieldCount,
+				oldIngredient.IngredientCount, inOld, newIngredient.IngredientCount, inNew)
+			changes = changes.add(name, ingredientName, FieldUnit,
+				oldIngredient.IngredientUnit, oldIngredient.IngredientUnit != "",
+				newIngredient.IngredientUnit, newIngredient.IngredientUnit != "")
+		}
+	}
+	return changes
+}
+
+// add appends the change of a field, if any, given its values and whether
+// it is set on either side.
+func (changes Changes) add(cake, ingredient string, field Field, old string, hasOld bool, new string, hasNew bool) Changes {
+	change := Change{Cake: cake, Ingredient: ingredient, Field: field, Old: old, New: new}
+	switch {
+	case hasOld && hasNew && old != new:
+		change.Kind = Changed
+	case hasNew && !hasOld:
+		change.Kind = Added
+	case hasOld && !hasNew:
+		change.Kind = Removed
+	default:
+		return changes
+	}
+	return append(changes, change)
+}
+
+// unionNames merges two sorted lists of names into one without repeats.
+func unionNames(a, b []string) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for len(a) != 0 || len(b) != 0 {
+		switch {
+		case len(b) == 0 || len(a) != 0 && a[0] < b[0]:
+			names, a = append(names, a[0]), a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			names, b = append(names, b[0]), b[1:]
+		default:
+			names, a, b = append(names, a[0]), a[1:], b[1:]
+		}
+	}
+	return names
+}
+
+// whole reports whether a change adds or removes a cake or an ingredient
+// rather than a single value of one.
+func (change Change) whole() bool {
+	return change.Kind != Changed && change.Field != FieldUnit
+}
+
+// covers reports whether other is part of the cake or ingredient that
+// change adds or removes as a whole.
+func (change Change) covers(other Change) bool {
+	return change.whole() && change.Kind == other.Kind && change.Cake == other.Cake &&
+		(change.Field == FieldTime || change.Ingredient == other.Ingredient)
+}
+
+// WriteText writes the changes as compareDB has always printed them, one
+// line each. A cake or an ingredient added or removed is a single line.
+func (changes Changes) WriteText(w io.Writer) error {
+	var outer Change
+	for _, change := range changes {
+		if outer.covers(change) {
+			continue
+		}
+		if change.whole() {
+			outer = change
+		}
+		if _, err := fmt.Fprintln(w, change.text()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (change Change) text() string {
+	switch change.Field {
+	case FieldTime:
+		if change.Kind == Changed {
+			return fmt.Sprintf("CHANGED cooking time for cake \"%s\" - \"%s\" instead of \"%s\"",
+				change.Cake, change.New, change.Old)
+		}
+		return fmt.Sprintf("%s cake \"%s\"", change.verb(), change.Cake)
+	case FieldCount:
+		if change.Kind == Changed {
+			return fmt.Sprintf("CHANGED unit count for ingredient \"%s\" for cake  \"%s\" - \"%s\" instead of \"%s\"",
+				change.Ingredient, change.Cake, change.New, change.Old)
+		}
+		return fmt.Sprintf("%s ingredient \"%s\" for cake  \"%s\"", change.verb(), change.Ingredient, change.Cake)
+	}
+	switch change.Kind {
+	case Added:
+		return fmt.Sprintf("ADDED unit \"%s\" for ingredient \"%s\" for cake \"%s\"", change.New, change.Ingredient, change.Cake)
+	case Removed:
+		return fmt.Sprintf("REMOVED unit \"%s\" for ingredient \"%s\" for cake \"%s\"", change.Old, change.Ingredient, change.Cake)
+	}
+	return fmt.Sprintf("CHANGED unit for ingredient \"%s\" for cake \"%s\" - \"%s\" instead of \"%s\"",
+		change.Ingredient, change.Cake, change.New, change.Old)
+}
+
+func (change Change) verb() string {
+	if change.Kind == Added {
+		return "ADDED"
+	}
+	return "REMOVED"
+}
+
+// WriteJSON writes the changes as a JSON array of records.
+func (changes Changes) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(changes)
+}
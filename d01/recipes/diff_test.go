@@ -0,0 +1,127 @@
+package recipes
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// csvCakes reads a database from the body of a CSV file, whose header the
+// tests leave out.
+func csvCakes(t *testing.T, rows string) map[string]Cake {
+	t.Helper()
+	cakes, err := readCSV(strings.NewReader("cake,time,ingredient,count,unit\n" + rows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cakes
+}
+
+// sameCake compares two cakes, whose ingredient maps may be nil or empty.
+func sameCake(a, b Cake) bool {
+	if a.Time != b.Time || len(a.IngredientMap) != len(b.IngredientMap) {
+		return false
+	}
+	for name, ingredient := range a.IngredientMap {
+		if other, ok := b.IngredientMap[name]; !ok || other != ingredient {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     Changes
+	}{
+		{"same", "A,5 min,Egg,2,\n", "A,5 min,Egg,2,\n", Changes{}},
+		{"added cake", "", "A,5 min,Egg,2,pcs\n", Changes{
+			{Kind: Added, Cake: "A", Field: FieldTime, New: "5 min"},
+			{Kind: Added, Cake: "A", Ingredient: "Egg", Field: FieldCount, New: "2"},
+			{Kind: Added, Cake: "A", Ingredient: "Egg", Field: FieldUnit, New: "pcs"},
+		}},
+		{"removed cake", "A,5 min,Egg,2,\n", "", Changes{
+			{Kind: Removed, Cake: "A", Field: FieldTime, Old: "5 min"},
+			{Kind: Removed, Cake: "A", Ingredient: "Egg", Field: FieldCount, Old: "2"},
+		}},
+		{"changed time", "A,5 min,,,\n", "A,6 min,,,\n", Changes{
+			{Kind: Changed, Cake: "A", Field: FieldTime, Old: "5 min", New: "6 min"},
+		}},
+		{"units", "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\nA,5 min,Oil,1,tbsp\n",
+			"A,5 min,Egg,2,pcs\nA,5 min,Milk,1,\nA,5 min,Oil,1,tsp\n", Changes{
+				{Kind: Added, Cake: "A", Ingredient: "Egg", Field: FieldUnit, New: "pcs"},
+				{Kind: Removed, Cake: "A", Ingredient: "Milk", Field: FieldUnit, Old: "cup"},
+				{Kind: Changed, Cake: "A", Ingredient: "Oil", Field: FieldUnit, Old: "tbsp", New: "tsp"},
+			}},
+		{"sorted by cake, then time, then ingredient", "B,1 min,Salt,1,\nA,5 min,Egg,2,\n",
+			"A,6 min,Egg,3,\nB,1 min,Salt,2,\n", Changes{
+				{Kind: Changed, Cake: "A", Field: FieldTime, Old: "5 min", New: "6 min"},
+				{Kind: Changed, Cake: "A", Ingredient: "Egg", Field: FieldCount, Old: "2", New: "3"},
+				{Kind: Changed, Cake: "B", Ingredient: "Salt", Field: FieldCount, Old: "1", New: "2"},
+			}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Diff(csvCakes(t, test.old), csvCakes(t, test.new))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Diff =\n%+v\nwant\n%+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSemanticDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     Changes
+	}{
+		{"same amount in other units", "A,5 min,Milk,3,tsp\n", "A,5 min,Milk,1,tablespoon\n", Changes{}},
+		{"mugs only equal mugs", "A,5 min,Milk,1,mug\n", "A,5 min,Milk,1,Mugs\n", Changes{}},
+		{"fraction and decimal", "A,5 min,Milk,1/2,cup\n", "A,5 min,Milk,0.5,Cups\n", Changes{}},
+		{"count in the same unit spelled another way", "A,5 min,Milk,1,cup\n", "A,5 min,Milk,2,cups\n", Changes{
+			{Kind: Changed, Cake: "A", Ingredient: "Milk", Field: FieldCount, Old: "1", New: "2"},
+		}},
+		{"other amount in other units", "A,5 min,Milk,1,cup\n", "A,5 min,Milk,1,tbsp\n", Changes{
+			{Kind: Changed, Cake: "A", Ingredient: "Milk", Field: FieldUnit, Old: "cup", New: "tbsp"},
+		}},
+		{"cups and mugs", "A,5 min,Milk,3,cups\n", "A,5 min,Milk,2,mugs\n", Changes{
+			{Kind: Changed, Cake: "A", Ingredient: "Milk", Field: FieldCount, Old: "3", New: "2"},
+			{Kind: Changed, Cake: "A", Ingredient: "Milk", Field: FieldUnit, Old: "cups", New: "mugs"},
+		}},
+		{"counts that are not numbers", "A,5 min,Salt,a little,\n", "A,5 min,Salt,some,\n", Changes{
+			{Kind: Changed, Cake: "A", Ingredient: "Salt", Field: FieldCount, Old: "a little", New: "some"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SemanticDiff(csvCakes(t, test.old), csvCakes(t, test.new))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SemanticDiff =\n%+v\nwant\n%+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	old := csvCakes(t, "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\nB,1 min,Salt,1,pinch\n")
+	new := csvCakes(t, "A,6 min,Egg,3,pcs\nA,6 min,Flour,2,cups\nC,2 min,Nuts,5,g\n")
+	want := `CHANGED cooking time for cake "A" - "6 min" instead of "5 min"
+CHANGED unit count for ingredient "Egg" for cake  "A" - "3" instead of "2"
+ADDED unit "pcs" for ingredient "Egg" for cake "A"
+ADDED ingredient "Flour" for cake  "A"
+REMOVED ingredient "Milk" for cake  "A"
+REMOVED cake "B"
+ADDED cake "C"
+`
+	var b bytes.Buffer
+	if err := Diff(old, new).WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Errorf("WriteText:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
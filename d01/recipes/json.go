@@ -0,0 +1,69 @@
+package recipes
+
+import (
+	"encoding/json"
+	"io"
+)
+
+func init() {
+	Register(NewCodec("json", []string{".json"}, sniffJSON, readJSON, writeJSON))
+}
+
+type RecipesJSON struct {
+	Cake []CakeJSON `json:"cake"`
+}
+
+type CakeJSON struct {
+	Name        string           `json:"name"`
+	Time        string           `json:"time"`
+	Ingredients []IngredientJSON `json:"ingredients"`
+}
+
+type IngredientJSON struct {
+	IngredientName  string `json:"ingredient_name"`
+	IngredientCount string `json:"ingredient_count"`
+	IngredientUnit  string `json:"ingredient_unit,omitempty"`
+}
+
+func sniffJSON(head []byte) bool {
+	return startsWith(head, "{")
+}
+
+func readJSON(r io.Reader) (map[string]Cake, error) {
+	var data RecipesJSON
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	cakes := make(map[string]Cake)
+	for _, cake := range data.Cake {
+		entry := Cake{Time: cake.Time, IngredientMap: make(map[string]Ingredient)}
+		for _, ingredient := range cake.Ingredients {
+			entry.IngredientMap[ingredient.IngredientName] = Ingredient{
+				IngredientCount: ingredient.IngredientCount,
+				IngredientUnit:  ingredient.IngredientUnit,
+			}
+		}
+		cakes[cake.Name] = entry
+	}
+	return cakes, nil
+}
+
+func writeJSON(w io.Writer, cakes map[string]Cake) error {
+	data := RecipesJSON{Cake: make([]CakeJSON, 0, len(cakes))}
+	for _, name := range cakeNames(cakes) {
+		cake := cakes[name]
+		entry := CakeJSON{Name: name, Time: cake.Time, Ingredients: make([]IngredientJSON, 0, len(cake.IngredientMap))}
+		for _, ingredientName := range ingredientNames(cake) {
+			ingredient := cake.IngredientMap[ingredientName]
+			entry.Ingredients = append(entry.Ingredients, IngredientJSON{
+				IngredientName:  ingredientName,
+				IngredientCount: ingredient.IngredientCount,
+				IngredientUnit:  ingredient.IngredientUnit,
+			})
+		}
+		data.Cake = append(data.Cake, entry)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(data)
+}
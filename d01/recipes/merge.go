@@ -0,0 +1,222 @@
+package recipes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MergeConflict is a value ours and theirs both changed, each its own way.
+// The merged database keeps ours. A nil value is not there: its cake or
+// ingredient is removed. A cake removed on one side and changed on the
+// other is a conflict on each value the other side changed or added: its
+// time and the quantities of its ingredients.
+type MergeConflict struct {
+	Cake       string  `json:"cake"`
+	Ingredient string  `json:"ingredient,omitempty"`
+	Field      Field   `json:"field"`
+	Reason     string  `json:"reason"`
+	Base       *string `json:"base"`
+	Ours       *string `json:"ours"`
+	Theirs     *string `json:"theirs"`
+}
+
+// MergeConflicts are in the order of Changes.
+type MergeConflicts []MergeConflict
+
+// FieldQuantity is the count and the unit of an ingredient, written as in
+// "3 g", which merge as one value: a count changed on one side and a unit
+// on the other together make a quantity neither side wrote.
+const FieldQuantity Field = "quantity"
+
+// Reasons of merge conflicts.
+const (
+	bothAdded     = "added on both sides"
+	bothChanged   = "changed on both sides"
+	removedByOurs = "removed by ours, changed by theirs"
+	removedByThem = "changed by ours, removed by theirs"
+)
+
+// Merge merges the changes ours and theirs each made to base. A value
+// changed on one side only, or the same way on both, is merged as is; any
+// other is one of the conflicts, and keeps ours.
+func Merge(base, ours, theirs map[string]Cake) (map[string]Cake, MergeConflicts) {
+	merged := make(map[string]Cake)
+	conflicts := MergeConflicts{}
+	for _, name := range unionNames(unionNames(cakeNames(base), cakeNames(ours)), cakeNames(theirs)) {
+		baseCake, inBase := base[name]
+		ourCake, inOurs := ours[name]
+		theirCake, inTheirs := theirs[name]
+		var baseTime *string
+		if inBase {
+			baseTime = &baseCake.Time
+		}
+		switch {
+		case inOurs && inTheirs:
+			var cakeConflicts MergeConflicts
+			merged[name], cakeConflicts = mergeCake(name, baseCake, baseTime, ourCake, theirCake)
+			conflicts = append(conflicts, cakeConflicts...)
+		case inOurs:
+			if !inBase {
+				merged[name] = copyCake(ourCake)
+			} else if removal := removalConflicts(name, baseCake, ourCake, true); len(removal) != 0 {
+				merged[name] = copyCake(ourCake)
+				conflicts = append(conflicts, removal...)
+			}
+		case inTheirs:
+			if !inBase {
+				merged[name] = copyCake(theirCake)
+			} else {
+				conflicts = append(conflicts, removalConflicts(name, baseCake, theirCake, false)...)
+			}
+		}
+	}
+	return merged, conflicts
+}
+
+// removalConflicts lists what the side that kept a cake changed in it while
+// the other side removed it: its time if that changed, and every ingredient
+// added or changed. Ingredients it removed agree with the removal, so a
+// cake whose only changes are removals merges as removed.
+func removalConflicts(name string, baseCake, keptCake Cake, ours bool) MergeConflicts {
+	var conflicts MergeConflicts
+	add := func(conflict MergeConflict, kept string) {
+		conflict.Cake, conflict.Reason = name, removedByOurs
+		if ours {
+			conflict.Reason, conflict.Ours = removedByThem, &kept
+		} else {
+			conflict.Theirs = &kept
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	if keptCake.Time != baseCake.Time {
+		baseTime := baseCake.Time
+		add(MergeConflict{Field: FieldTime, Base: &baseTime}, keptCake.Time)
+	}
+	for _, ingredientName := range ingredientNames(keptCake) {
+		keptIngredient := keptCake.IngredientMap[ingredientName]
+		baseIngredient, inBase := baseCake.IngredientMap[ingredientName]
+		if inBase && baseIngredient == keptIngredient {
+			continue
+		}
+		var baseQuantity *string
+		if inBase {
+			text := quantityText(baseIngredient)
+			baseQuantity = &text
+		}
+		add(MergeConflict{Ingredient: ingredientName, Field: FieldQuantity, Base: baseQuantity}, quantityText(keptIngredient))
+	}
+	return conflicts
+}
+
+// mergeCake merges a cake both sides have. baseTime is nil when base does
+// not have it.
+func mergeCake(name string, baseCake Cake, baseTime *string, ourCake, theirCake Cake) (Cake, MergeConflicts) {
+	var conflicts MergeConflicts
+	time, conflict := mergeValue(name, "", FieldTime, baseTime, &ourCake.Time, &theirCake.Time)
+	if conflict != nil {
+		conflicts = append(conflicts, *conflict)
+	}
+	cake := Cake{Time: *time, IngredientMap: make(map[string]Ingredient)}
+	names := unionNames(unionNames(ingredientNames(baseCake), ingredientNames(ourCake)), ingredientNames(theirCake))
+	for _, ingredientName := range names {
+		baseIngredient, inBase := baseCake.IngredientMap[ingredientName]
+		ourIngredient, inOurs := ourCake.IngredientMap[ingredientName]
+		theirIngredient, inTheirs := theirCake.IngredientMap[ingredientName]
+		var baseQuantity *string
+		if inBase {
+			text := quantityText(baseIngredient)
+			baseQuantity = &text
+		}
+		ourQuantity, theirQuantity := quantityText(ourIngredient), quantityText(theirIngredient)
+		switch {
+		case inOurs && inTheirs:
+			quantity, conflict := mergeValue(name, ingredientName, FieldQuantity, baseQuantity, &ourQuantity, &theirQuantity)
+			if conflict != nil {
+				conflicts = append(conflicts, *conflict)
+			}
+			// mergeValue returns the side it keeps, not a copy of it.
+			if quantity == &theirQuantity {
+				cake.IngredientMap[ingredientName] = theirIngredient
+			} else {
+				cake.IngredientMap[ingredientName] = ourIngredient
+			}
+		case inOurs:
+			if !inBase || baseIngredient != ourIngredient {
+				cake.IngredientMap[ingredientName] = ourIngredient
+			}
+			if inBase && baseIngredient != ourIngredient {
+				conflicts = append(conflicts, MergeConflict{Cake: name, Ingredient: ingredientName, Field: FieldQuantity,
+					Reason: removedByThem, Base: baseQuantity, Ours: &ourQuantity})
+			}
+		case inTheirs:
+			if !inBase {
+				cake.IngredientMap[ingredientName] = theirIngredient
+			} else if baseIngredient != theirIngredient {
+				conflicts = append(conflicts, MergeConflict{Cake: name, Ingredient: ingredientName, Field: FieldQuantity,
+					Reason: removedByOurs, Base: baseQuantity, Theirs: &theirQuantity})
+			}
+		}
+	}
+	return cake, conflicts
+}
+
+// mergeValue merges one value, nil where it is not there, and returns the
+// conflict if both sides changed it differently.
+func mergeValue(cake, ingredient string, field Field, base, ours, theirs *string) (*string, *MergeConflict) {
+	switch {
+	case equalValues(ours, theirs), equalValues(base, theirs):
+		return ours, nil
+	case equalValues(base, ours):
+		return theirs, nil
+	}
+	reason := bothChanged
+	if base == nil {
+		reason = bothAdded
+	}
+	return ours, &MergeConflict{Cake: cake, Ingredient: ingredient, Field: field, Reason: reason,
+		Base: base, Ours: ours, Theirs: theirs}
+}
+
+func equalValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// quantityText is the count of an ingredient followed by its unit, if any.
+func quantityText(ingredient Ingredient) string {
+	if ingredient.IngredientUnit == "" {
+		return ingredient.IngredientCount
+	}
+	return ingredient.IngredientCount + " " + ingredient.IngredientUnit
+}
+
+// WriteText writes the conflicts one line each.
+func (conflicts MergeConflicts) WriteText(w io.Writer) error {
+	for _, conflict := range conflicts {
+		where := Change{Cake: conflict.Cake, Ingredient: conflict.Ingredient, Field: conflict.Field}.where()
+		_, err := fmt.Fprintf(w, "CONFLICT %s: %s - base %s, ours %s, theirs %s\n", where, conflict.Reason,
+			conflictValue(conflict.Base), conflictValue(conflict.Ours), conflictValue(conflict.Theirs))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func conflictValue(value *string) string {
+	if value == nil {
+		return "none"
+	}
+	return strconv.Quote(*value)
+}
+
+// WriteJSON writes the conflicts as a JSON array of records.
+func (conflicts MergeConflicts) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(conflicts)
+}
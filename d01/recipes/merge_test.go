@@ -0,0 +1,110 @@
+package recipes
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		merged             string
+		conflicts          string
+	}{
+		{
+			name: "ours changed", base: "A,5 min,Egg,2,\n", ours: "A,6 min,Egg,3,\n", theirs: "A,5 min,Egg,2,\n",
+			merged: "A,6 min,Egg,3,\n",
+		},
+		{
+			name: "theirs changed", base: "A,5 min,Egg,2,\n", ours: "A,5 min,Egg,2,\n", theirs: "A,6 min,Egg,2,pcs\n",
+			merged: "A,6 min,Egg,2,pcs\n",
+		},
+		{
+			name: "both changed the same", base: "A,5 min,Egg,2,\n", ours: "A,6 min,Egg,3,\n", theirs: "A,6 min,Egg,3,\n",
+			merged: "A,6 min,Egg,3,\n",
+		},
+		{
+			name: "both changed differently", base: "A,5 min,Egg,2,\n", ours: "A,6 min,Egg,3,\n", theirs: "A,7 min,Egg,4,\n",
+			merged: "A,6 min,Egg,3,\n",
+			conflicts: `CONFLICT cake "A" time: changed on both sides - base "5 min", ours "6 min", theirs "7 min"` + "\n" +
+				`CONFLICT cake "A" ingredient "Egg" quantity: changed on both sides - base "2", ours "3", theirs "4"` + "\n",
+		},
+		{
+			name: "count on one side, unit on the other", base: "A,5 min,Flour,1,g\n", ours: "A,5 min,Flour,1,kg\n", theirs: "A,5 min,Flour,3,g\n",
+			merged:    "A,5 min,Flour,1,kg\n",
+			conflicts: `CONFLICT cake "A" ingredient "Flour" quantity: changed on both sides - base "1 g", ours "1 kg", theirs "3 g"` + "\n",
+		},
+		{
+			name: "unit only", base: "A,5 min,Flour,1,g\n", ours: "A,5 min,Flour,1,g\n", theirs: "A,5 min,Flour,1,kg\n",
+			merged: "A,5 min,Flour,1,kg\n",
+		},
+		{
+			name: "cake added on both sides the same", base: "", ours: "B,1 min,Nuts,5,g\n", theirs: "B,1 min,Nuts,5,g\n",
+			merged: "B,1 min,Nuts,5,g\n",
+		},
+		{
+			name: "cake added on both sides differently", base: "", ours: "B,1 min,Nuts,5,g\n", theirs: "B,2 min,Nuts,6,g\n",
+			merged: "B,1 min,Nuts,5,g\n",
+			conflicts: `CONFLICT cake "B" time: added on both sides - base none, ours "1 min", theirs "2 min"` + "\n" +
+				`CONFLICT cake "B" ingredient "Nuts" quantity: added on both sides - base none, ours "5 g", theirs "6 g"` + "\n",
+		},
+		{
+			name: "ingredients added on each side", base: "A,5 min,Egg,2,\n", ours: "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\n", theirs: "A,5 min,Egg,2,\nA,5 min,Salt,1,pinch\n",
+			merged: "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\nA,5 min,Salt,1,pinch\n",
+		},
+		{
+			name: "cake removed by ours, changed by theirs", base: "A,5 min,Egg,2,\n", ours: "", theirs: "A,5 min,Egg,3,\n",
+			merged:    "",
+			conflicts: `CONFLICT cake "A" ingredient "Egg" quantity: removed by ours, changed by theirs - base "2", ours none, theirs "3"` + "\n",
+		},
+		{
+			name: "cake removed by ours, added to by theirs", base: "A,5 min,Egg,2,\n", ours: "", theirs: "A,6 min,Egg,2,\nA,6 min,Milk,1,cup\n",
+			merged: "",
+			conflicts: `CONFLICT cake "A" time: removed by ours, changed by theirs - base "5 min", ours none, theirs "6 min"` + "\n" +
+				`CONFLICT cake "A" ingredient "Milk" quantity: removed by ours, changed by theirs - base none, ours none, theirs "1 cup"` + "\n",
+		},
+		{
+			name: "cake removed by theirs, an ingredient removed by ours", base: "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\n", ours: "A,5 min,Egg,2,\n", theirs: "",
+			merged: "",
+		},
+		{
+			name: "cake changed by ours, removed by theirs", base: "A,5 min,Egg,2,\n", ours: "A,6 min,Egg,2,\n", theirs: "",
+			merged:    "A,6 min,Egg,2,\n",
+			conflicts: `CONFLICT cake "A" time: changed by ours, removed by theirs - base "5 min", ours "6 min", theirs none` + "\n",
+		},
+		{
+			name: "cake removed unchanged", base: "A,5 min,Egg,2,\nB,1 min,Nuts,5,g\n", ours: "A,5 min,Egg,2,\n", theirs: "B,1 min,Nuts,5,g\n",
+			merged: "",
+		},
+		{
+			name: "ingredient removed by ours, changed by theirs", base: "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\n", ours: "A,5 min,Egg,2,\n", theirs: "A,5 min,Egg,2,\nA,5 min,Milk,2,cups\n",
+			merged:    "A,5 min,Egg,2,\n",
+			conflicts: `CONFLICT cake "A" ingredient "Milk" quantity: removed by ours, changed by theirs - base "1 cup", ours none, theirs "2 cups"` + "\n",
+		},
+		{
+			name: "ingredient changed by ours, removed by theirs", base: "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\n", ours: "A,5 min,Egg,2,\nA,5 min,Milk,1,mug\n", theirs: "A,5 min,Egg,2,\n",
+			merged:    "A,5 min,Egg,2,\nA,5 min,Milk,1,mug\n",
+			conflicts: `CONFLICT cake "A" ingredient "Milk" quantity: changed by ours, removed by theirs - base "1 cup", ours "1 mug", theirs none` + "\n",
+		},
+		{
+			name: "ingredient removed unchanged", base: "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\n", ours: "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\n", theirs: "A,5 min,Egg,2,\n",
+			merged: "A,5 min,Egg,2,\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts := Merge(csvCakes(t, test.base), csvCakes(t, test.ours), csvCakes(t, test.theirs))
+			if diff := Diff(merged, csvCakes(t, test.merged)); len(diff) != 0 {
+				t.Errorf("merged differs by %+v", diff)
+			}
+			var b bytes.Buffer
+			if err := conflicts.WriteText(&b); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != test.conflicts {
+				t.Errorf("conflicts\n%s\nwant\n%s", b.String(), test.conflicts)
+			}
+		})
+	}
+}
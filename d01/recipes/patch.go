@@ -0,0 +1,227 @@
+package recipes
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WritePatch writes the changes as a unified patch from oldName to newName.
+// Every cake with changes is a hunk, and every value a line: removed with
+// "-", added with "+", and changed as its old line followed by the new one.
+//
+//	--- original_database.xml
+//	+++ stolen_database.json
+//	@@ cake "Red Velvet Strawberry Cake" @@
+//	-time "40 min"
+//	+time "45 min"
+//	+ingredient "Coffee beans" count "7.5"
+//	+ingredient "Coffee beans" unit "cups"
+//
+// Nothing is written when there are no changes.
+func (changes Changes) WritePatch(w io.Writer, oldName, newName string) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+	for i, change := range changes {
+		if i == 0 || changes[i-1].Cake != change.Cake {
+			fmt.Fprintf(out, "@@ cake %s @@\n", strconv.Quote(change.Cake))
+		}
+		if change.Kind != Added {
+			fmt.Fprintf(out, "-%s\n", change.patchLine(change.Old))
+		}
+		if change.Kind != Removed {
+			fmt.Fprintf(out, "+%s\n", change.patchLine(change.New))
+		}
+	}
+	return out.Flush()
+}
+
+// patchLine is a value of the change as a patch line, without its sign.
+func (change Change) patchLine(value string) string {
+	if change.Ingredient == "" {
+		return fmt.Sprintf("%s %s", change.Field, strconv.Quote(value))
+	}
+	return fmt.Sprintf("ingredient %s %s %s", strconv.Quote(change.Ingredient), change.Field, strconv.Quote(value))
+}
+
+// ReadChanges reads changes saved by compareDB, either as JSON or as a
+// patch. The text format leaves out what an added or removed cake holds,
+// so it cannot be read back.
+func ReadChanges(r io.Reader) (Changes, error) {
+	reader := bufio.NewReaderSize(r, sniffSize)
+	head, _ := reader.Peek(sniffSize)
+	if startsWith(head, "[") {
+		return readJSONChanges(reader)
+	}
+	return ReadPatch(reader)
+}
+
+func readJSONChanges(r io.Reader) (Changes, error) {
+	var changes Changes
+	if err := json.NewDecoder(r).Decode(&changes); err != nil {
+		return nil, err
+	}
+	for i, change := range changes {
+		if err := change.check(); err != nil {
+			return nil, fmt.Errorf("change %d: %w", i+1, err)
+		}
+	}
+	return changes, nil
+}
+
+// check reports a change no diff could have made.
+func (change Change) check() error {
+	switch change.Kind {
+	case Added, Removed, Changed:
+	default:
+		return fmt.Errorf("unknown kind %q", change.Kind)
+	}
+	return change.checkField()
+}
+
+// checkField reports a field that is not one of the cake or ingredient the
+// change is about.
+func (change Change) checkField() error {
+	switch change.Field {
+	case FieldTime:
+		if change.Ingredient != "" {
+			return errors.New("time of an ingredient")
+		}
+	case FieldCount, FieldUnit:
+		if change.Ingredient == "" {
+			return fmt.Errorf("%s without an ingredient", change.Field)
+		}
+	default:
+		return fmt.Errorf("unknown field %q", change.Field)
+	}
+	return nil
+}
+
+// patchValue is a line of a patch: one value and whether it goes or comes.
+type patchValue struct {
+	line    int
+	removed bool
+	change  Change
+	value   string
+}
+
+// ReadPatch reads a patch written by WritePatch. A removed line followed by
+// an added one for the same value is a change of it.
+func ReadPatch(r io.Reader) (Changes, error) {
+	scanner := bufio.NewScanner(r)
+	var values []patchValue
+	cake, inHunk := "", false
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case text == "" || strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "+++ "):
+		case strings.HasPrefix(text, "@@ "):
+			rest, err := patchWord(strings.TrimPrefix(text, "@@ "), "cake")
+			if err == nil {
+				cake, rest, err = patchQuoted(rest)
+			}
+			if err == nil && rest != " @@" {
+				err = errors.New("hunk header does not end with @@")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			inHunk = true
+		case text[0] == '-' || text[0] == '+':
+			if !inHunk {
+				return nil, fmt.Errorf("line %d: value outside of a hunk", line)
+			}
+			value, err := parsePatchValue(text[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			value.line, value.removed, value.change.Cake = line, text[0] == '-', cake
+			values = append(values, value)
+		default:
+			return nil, fmt.Errorf("line %d: not a patch line", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pairPatchValues(values)
+}
+
+// parsePatchValue parses a patch line without its sign.
+func parsePatchValue(text string) (patchValue, error) {
+	var value patchValue
+	var err error
+	if rest, ingredientErr := patchWord(text, "ingredient"); ingredientErr == nil {
+		value.change.Ingredient, text, err = patchQuoted(rest)
+		if err != nil {
+			return value, err
+		}
+		text = strings.TrimPrefix(text, " ")
+	}
+	field, rest, ok := strings.Cut(text, " ")
+	if !ok {
+		return value, errors.New("no value")
+	}
+	value.change.Field = Field(field)
+	value.value, rest, err = patchQuoted(rest)
+	if err != nil {
+		return value, err
+	}
+	if rest != "" {
+		return value, fmt.Errorf("unexpected %q after the value", rest)
+	}
+	return value, value.change.checkField()
+}
+
+// pairPatchValues makes changes of the values of a patch.
+func pairPatchValues(values []patchValue) (Changes, error) {
+	changes := Changes{}
+	for i := 0; i < len(values); i++ {
+		value := values[i]
+		change := value.change
+		switch {
+		case value.removed && i+1 < len(values) && !values[i+1].removed && values[i+1].change == change:
+			change.Kind, change.Old, change.New = Changed, value.value, values[i+1].value
+			i++
+		case value.removed:
+			change.Kind, change.Old = Removed, value.value
+		default:
+			change.Kind, change.New = Added, value.value
+		}
+		if n := len(changes); n != 0 && changes[n-1].sameValue(change) {
+			return nil, fmt.Errorf("line %d: %s given twice", value.line, change.where())
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// sameValue reports whether two changes are about the same value.
+func (change Change) sameValue(other Change) bool {
+	return change.Cake == other.Cake && change.Ingredient == other.Ingredient && change.Field == other.Field
+}
+
+// patchWord takes word and a space off the start of text.
+func patchWord(text, word string) (string, error) {
+	if !strings.HasPrefix(text, word+" ") {
+		return "", fmt.Errorf("no %s", word)
+	}
+	return text[len(word)+1:], nil
+}
+
+// patchQuoted takes a quoted string off the start of text.
+func patchQuoted(text string) (string, string, error) {
+	quoted, err := strconv.QuotedPrefix(text)
+	if err != nil {
+		return "", "", fmt.Errorf("bad quoted string at %q", text)
+	}
+	value, err := strconv.Unquote(quoted)
+	return value, text[len(quoted):], err
+}
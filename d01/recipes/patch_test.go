@@ -0,0 +1,71 @@
+package recipes
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPatchRoundTrip(t *testing.T) {
+	old := csvCakes(t, "A,5 min,Egg,2,\nA,5 min,Milk,1,cup\n\"B \"\"quoted\"\"\",1 min,\"Salt, fine\",1,pinch\n")
+	new := csvCakes(t, "A,6 min,Egg,3,pcs\nA,6 min,Flour,1 1/2,cups\nC,2 min,Nuts,5,g\n")
+	changes := Diff(old, new)
+	var b bytes.Buffer
+	if err := changes.WritePatch(&b, "old.csv", "new.csv"); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPatch(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, b.String())
+	}
+	if !reflect.DeepEqual(read, changes) {
+		t.Errorf("read back\n%+v\nwant\n%+v\nfrom:\n%s", read, changes, b.String())
+	}
+	b.Reset()
+	if err := changes.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if read, err = ReadChanges(&b); err != nil || !reflect.DeepEqual(read, changes) {
+		t.Errorf("read back from JSON %+v, %v", read, err)
+	}
+}
+
+func TestWritePatchWithoutChanges(t *testing.T) {
+	var b bytes.Buffer
+	if err := (Changes{}).WritePatch(&b, "a", "b"); err != nil || b.Len() != 0 {
+		t.Errorf("wrote %q, %v", b.String(), err)
+	}
+}
+
+func TestReadPatchErrors(t *testing.T) {
+	tests := []struct {
+		name, patch, err string
+	}{
+		{"value outside a hunk", "--- a\n+++ b\n-time \"x\"\n", "line 3: value outside of a hunk"},
+		{"bad hunk header", "@@ cake A @@\n", `line 1: bad quoted string at "A @@"`},
+		{"unterminated hunk header", "@@ cake \"A\"\n", "line 1: hunk header does not end with @@"},
+		{"unquoted value", "@@ cake \"A\" @@\n+time 5\n", `line 2: bad quoted string at "5"`},
+		{"unknown field", "@@ cake \"A\" @@\n+ingredient \"Egg\" weight \"2\"\n", `line 2: unknown field "weight"`},
+		{"time of an ingredient", "@@ cake \"A\" @@\n+ingredient \"Egg\" time \"2\"\n", "line 2: time of an ingredient"},
+		{"count of a cake", "@@ cake \"A\" @@\n+count \"2\"\n", "line 2: count without an ingredient"},
+		{"trailing text", "@@ cake \"A\" @@\n+time \"5\" min\n", `line 2: unexpected " min" after the value`},
+		{"value given twice", "@@ cake \"A\" @@\n+time \"5\"\n+time \"6\"\n", `line 3: cake "A" time given twice`},
+		{"not a patch line", "@@ cake \"A\" @@\ntime \"5\"\n", "line 2: not a patch line"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadPatch(strings.NewReader(test.patch))
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestReadChangesRejectsBadJSON(t *testing.T) {
+	_, err := ReadChanges(strings.NewReader(`[{"kind": "moved", "cake": "A", "field": "time"}]`))
+	if err == nil || err.Error() != `change 1: unknown kind "moved"` {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package recipes
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Dimension is what a unit measures. Units of the same dimension convert
+// into each other; a unit without one only equals itself.
+type Dimension string
+
+const (
+	Volume Dimension = "volume"
+	Mass   Dimension = "mass"
+	Pieces Dimension = "pieces"
+)
+
+// Unit is a unit of ingredient counts. Size is how many of the base unit
+// of its dimension it holds: millilitres, grams or pieces.
+type Unit struct {
+	Name      string
+	Dimension Dimension
+	Size      *big.Rat
+}
+
+// units are the units recipes know, by canonical name, with their other
+// spellings. Plurals of every spelling are understood as well. A mug has
+// no standard size, so like a pinch it only equals itself.
+var units = []struct {
+	unit    Unit
+	aliases []string
+}{
+	{Unit{"ml", Volume, big.NewRat(1, 1)}, []string{"milliliter", "millilitre"}},
+	{Unit{"l", Volume, big.NewRat(1000, 1)}, []string{"liter", "litre"}},
+	{Unit{"tsp", Volume, big.NewRat(5, 1)}, []string{"teaspoon"}},
+	{Unit{"tbsp", Volume, big.NewRat(15, 1)}, []string{"tablespoon"}},
+	{Unit{"fl oz", Volume, big.NewRat(30, 1)}, []string{"fluid ounce"}},
+	{Unit{"cup", Volume, big.NewRat(240, 1)}, nil},
+	{Unit{"mg", Mass, big.NewRat(1, 1000)}, []string{"milligram", "milligramme"}},
+	{Unit{"g", Mass, big.NewRat(1, 1)}, []string{"gram", "gramme"}},
+	{Unit{"kg", Mass, big.NewRat(1000, 1)}, []string{"kilogram", "kilogramme"}},
+	{Unit{"oz", Mass, big.NewRat(28349523125, 1000000000)}, []string{"ounce"}},
+	{Unit{"lb", Mass, big.NewRat(45359237, 100000)}, []string{"pound"}},
+	{Unit{"", Pieces, big.NewRat(1, 1)}, []string{"piece", "pc", "pcs"}},
+	{Unit{"pinch", "", nil}, nil},
+	{Unit{"dash", "", nil}, nil},
+	{Unit{"mug", "", nil}, nil},
+}
+
+// LookupUnit finds a unit by any of its spellings, in any case and with or
+// without a plural s or es or a final dot.
+func LookupUnit(name string) (Unit, bool) {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	candidates := []string{name}
+	for _, plural := range []string{"s", "es"} {
+		if singular := strings.TrimSuffix(name, plural); singular != name && singular != "" {
+			candidates = append(candidates, singular)
+		}
+	}
+	for _, candidate := range candidates {
+		for _, known := range units {
+			if candidate == known.unit.Name {
+				return known.unit, true
+			}
+			for _, alias := range known.aliases {
+				if candidate == alias {
+					return known.unit, true
+				}
+			}
+		}
+	}
+	return Unit{}, false
+}
+
+// ParseUnit is LookupUnit for units that may be unknown, which are kept
+// as they are spelled, in lower case, without a dimension.
+func ParseUnit(name string) Unit {
+	if unit, ok := LookupUnit(name); ok {
+		return unit
+	}
+	return Unit{Name: strings.ToLower(strings.TrimSpace(name))}
+}
+
+// ParseCount parses an ingredient count: a whole or decimal number, a
+// fraction like 1/2, or a whole number and a fraction like 1 1/2.
+func ParseCount(count string) (*big.Rat, error) {
+	fields := strings.Fields(count)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("bad count %q", count)
+	}
+	amount, ok := new(big.Rat).SetString(fields[0])
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("bad count %q", count)
+	}
+	if len(fields) == 2 {
+		fraction, ok := new(big.Rat).SetString(fields[1])
+		if !ok || !amount.IsInt() || !strings.Contains(fields[1], "/") ||
+			fraction.Sign() < 0 || fraction.Cmp(big.NewRat(1, 1)) >= 0 {
+			return nil, fmt.Errorf("bad count %q", count)
+		}
+		amount.Add(amount, fraction)
+	}
+	return amount, nil
+}
+
+// FormatCount writes an amount as a whole or decimal number, or as a
+// fraction when it has no finite decimal form.
+func FormatCount(amount *big.Rat) string {
+	if amount.IsInt() {
+		return amount.RatString()
+	}
+	denom := new(big.Int).Set(amount.Denom())
+	digits := 0
+	for _, factor := range []int64{2, 5} {
+		n := 0
+		for new(big.Int).Mod(denom, big.NewInt(factor)).Sign() == 0 {
+			denom.Quo(denom, big.NewInt(factor))
+			n++
+		}
+		if n > digits {
+			digits = n
+		}
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return amount.RatString()
+	}
+	return strings.TrimRight(amount.FloatString(digits), "0")
+}
+
+// Quantity is how much of an ingredient a cake takes.
+type Quantity struct {
+	Amount *big.Rat
+	Unit   Unit
+}
+
+// ParseQuantity parses the count and the unit of an ingredient.
+func ParseQuantity(count, unit string) (Quantity, error) {
+	amount, err := ParseCount(count)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{Amount: amount, Unit: ParseUnit(unit)}, nil
+}
+
+// Quantity is the parsed count and unit of the ingredient.
+func (ingredient Ingredient) Quantity() (Quantity, error) {
+	return ParseQuantity(ingredient.IngredientCount, ingredient.IngredientUnit)
+}
+
+// In converts the quantity to unit, which has to measure the same.
+func (q Quantity) In(unit Unit) (Quantity, error) {
+	if q.Unit.Name == unit.Name {
+		return q, nil
+	}
+	if q.Unit.Dimension == "" || q.Unit.Dimension != unit.Dimension {
+		return Quantity{}, fmt.Errorf("cannot convert %s to %s", q, unitName(unit))
+	}
+	amount := new(big.Rat).Mul(q.Amount, q.Unit.Size)
+	return Quantity{Amount: amount.Quo(amount, unit.Size), Unit: unit}, nil
+}
+
+// Equal reports whether two quantities are the same amount, once in the
+// same unit.
+func (q Quantity) Equal(other Quantity) bool {
+	converted, err := other.In(q.Unit)
+	return err == nil && q.Amount.Cmp(converted.Amount) == 0
+}
+
+func (q Quantity) String() string {
+	if q.Unit.Name == "" {
+		return FormatCount(q.Amount)
+	}
+	return FormatCount(q.Amount) + " " + q.Unit.Name
+}
+
+func unitName(unit Unit) string {
+	if unit.Name == "" {
+		return "pieces"
+	}
+	return unit.Name
+}
+
+// Normalize returns the ingredient with its count and unit in canonical
+// form, converted to the first of to that measures the same, if any. An
+// ingredient whose count does not parse is returned as it is, with an
+// error.
+func (ingredient Ingredient) Normalize(to []Unit) (Ingredient, error) {
+	q, err := ingredient.Quantity()
+	if err != nil {
+		return ingredient, err
+	}
+	for _, unit := range to {
+		if converted, err := q.In(unit); err == nil {
+			q = converted
+			break
+		}
+	}
+	return Ingredient{IngredientCount: FormatCount(q.Amount), IngredientUnit: q.Unit.Name}, nil
+}
@@ -0,0 +1,168 @@
+package recipes
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		count string
+		want  *big.Rat
+	}{
+		{"3", big.NewRat(3, 1)},
+		{" 2 ", big.NewRat(2, 1)},
+		{"0.25", big.NewRat(1, 4)},
+		{"1/2", big.NewRat(1, 2)},
+		{"1 1/2", big.NewRat(3, 2)},
+		{"2 3/4", big.NewRat(11, 4)},
+		{"", nil},
+		{"a few", nil},
+		{"-1", nil},
+		{"1.5 1/2", nil},
+		{"1 3/2", nil},
+		{"1 0.5", nil},
+		{"1 1/2 1/4", nil},
+	}
+	for _, test := range tests {
+		got, err := ParseCount(test.count)
+		switch {
+		case test.want == nil && err == nil:
+			t.Errorf("ParseCount(%q) = %s, want an error", test.count, got)
+		case test.want != nil && (err != nil || got.Cmp(test.want) != 0):
+			t.Errorf("ParseCount(%q) = %v, %v; want %s", test.count, got, err, test.want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		amount *big.Rat
+		want   string
+	}{
+		{big.NewRat(3, 1), "3"},
+		{big.NewRat(0, 1), "0"},
+		{big.NewRat(3, 2), "1.5"},
+		{big.NewRat(1, 8), "0.125"},
+		{big.NewRat(1, 3), "1/3"},
+		{big.NewRat(5, 6), "5/6"},
+	}
+	for _, test := range tests {
+		if got := FormatCount(test.amount); got != test.want {
+			t.Errorf("FormatCount(%s) = %q, want %q", test.amount, got, test.want)
+		}
+	}
+}
+
+func TestLookupUnit(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"g", "g", true},
+		{"grams", "g", true},
+		{"Kilogramme", "kg", true},
+		{"CUPS", "cup", true},
+		{"tbsp.", "tbsp", true},
+		{"Tablespoons", "tbsp", true},
+		{"fluid ounces", "fl oz", true},
+		{"lbs", "lb", true},
+		{"pinches", "pinch", true},
+		{"dashes", "dash", true},
+		{"pcs", "", true},
+		{"pieces", "", true},
+		{"", "", true},
+		{"s", "", false},
+		{"es", "", false},
+		{"glass", "", false},
+	}
+	for _, test := range tests {
+		unit, ok := LookupUnit(test.name)
+		if ok != test.ok || unit.Name != test.want {
+			t.Errorf("LookupUnit(%q) = %q, %v; want %q, %v", test.name, unit.Name, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestQuantityIn(t *testing.T) {
+	tests := []struct {
+		count, unit, to string
+		want            string
+	}{
+		{"3", "cups", "tbsp", "48 tbsp"},
+		{"2", "mugs", "Mug", "2 mug"},
+		{"1", "mug", "cup", ""},
+		{"1 1/2", "tbsp", "tsp", "4.5 tsp"},
+		{"1", "l", "cup", "25/6 cup"},
+		{"1", "lb", "g", "453.59237 g"},
+		{"2", "kg", "Kilograms", "2 kg"},
+		{"1", "pinch", "pinch", "1 pinch"},
+		{"1", "cup", "g", ""},
+		{"1", "pinch", "dash", ""},
+		{"1", "glass", "ml", ""},
+	}
+	for _, test := range tests {
+		q, err := ParseQuantity(test.count, test.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := q.In(ParseUnit(test.to))
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("%s in %s = %s, want an error", q, test.to, got)
+		case test.want != "" && (err != nil || got.String() != test.want):
+			t.Errorf("%s in %s = %v, %v; want %s", q, test.to, got, err, test.want)
+		}
+	}
+}
+
+func TestQuantityEqual(t *testing.T) {
+	tests := []struct {
+		a, b [2]string
+		want bool
+	}{
+		{[2]string{"3", "tsp"}, [2]string{"1", "tbsp"}, true},
+		{[2]string{"2", "mugs"}, [2]string{"2", "mug"}, true},
+		{[2]string{"3", "cups"}, [2]string{"2", "mugs"}, false},
+		{[2]string{"1/2", "cup"}, [2]string{"0.5", "Cups"}, true},
+		{[2]string{"1000", "g"}, [2]string{"1", "kg"}, true},
+		{[2]string{"2", ""}, [2]string{"2", "pcs"}, true},
+		{[2]string{"1", "cup"}, [2]string{"1", "mug"}, false},
+		{[2]string{"1", "cup"}, [2]string{"240", "g"}, false},
+		{[2]string{"1", "pinch"}, [2]string{"1", "dash"}, false},
+	}
+	for _, test := range tests {
+		a, err := ParseQuantity(test.a[0], test.a[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseQuantity(test.b[0], test.b[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Equal(b) != test.want || b.Equal(a) != test.want {
+			t.Errorf("%s equal to %s is not %v", a, b, test.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	to := []Unit{ParseUnit("ml"), ParseUnit("g")}
+	tests := []struct {
+		in, want Ingredient
+		err      bool
+	}{
+		{Ingredient{IngredientCount: "1 1/2", IngredientUnit: "Cups"}, Ingredient{IngredientCount: "360", IngredientUnit: "ml"}, false},
+		{Ingredient{IngredientCount: "0.5", IngredientUnit: "kg"}, Ingredient{IngredientCount: "500", IngredientUnit: "g"}, false},
+		{Ingredient{IngredientCount: "2", IngredientUnit: "pieces"}, Ingredient{IngredientCount: "2"}, false},
+		{Ingredient{IngredientCount: "1/3", IngredientUnit: "Pinches"}, Ingredient{IngredientCount: "1/3", IngredientUnit: "pinch"}, false},
+		{Ingredient{IngredientCount: "some", IngredientUnit: "g"}, Ingredient{IngredientCount: "some", IngredientUnit: "g"}, true},
+	}
+	for _, test := range tests {
+		got, err := test.in.Normalize(to)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("Normalize(%+v) = %+v, %v; want %+v", test.in, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package recipes reads and writes recipe databases. Every format is a
+// Codec decoding into and encoding from the same canonical model, a map of
+// cakes by name, and codecs are picked by file extension or by content.
+package recipes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type Ingredient struct {
+	IngredientCount string
+	IngredientUnit  string
+}
+
+type Cake struct {
+	Time          string
+	IngredientMap map[string]Ingredient
+}
+
+type MapReciepes struct {
+	Cake map[string]Cake
+}
+
+// sniffSize is how much of a file codecs get to recognise it by.
+const sniffSize = 512
+
+// ReadFile reads a recipe database with the codec its extension names or,
+// failing that, the one that recognises its content. It also returns that
+// codec.
+func ReadFile(fileName string) (*MapReciepes, Codec, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, sniffSize)
+	head, _ := reader.Peek(sniffSize)
+	codec, err := Detect(fileName, head)
+	if err != nil {
+		return nil, nil, err
+	}
+	cakes, err := codec.Read(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return &MapReciepes{Cake: cakes}, codec, nil
+}
+
+// cakeNames returns the names of the cakes in order, which is how every
+// codec writes them so that output does not depend on map order.
+func cakeNames(cakes map[string]Cake) []string {
+	names := make([]string, 0, len(cakes))
+	for name := range cakes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ingredientNames is cakeNames for the ingredients of a cake.
+func ingredientNames(cake Cake) []string {
+	names := make([]string, 0, len(cake.IngredientMap))
+	for name := range cake.IngredientMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copyCake returns a cake that shares no map with cake.
+func copyCake(cake Cake) Cake {
+	ingredients := make(map[string]Ingredient, len(cake.IngredientMap))
+	for name, ingredient := range cake.IngredientMap {
+		ingredients[name] = ingredient
+	}
+	return Cake{Time: cake.Time, IngredientMap: ingredients}
+}
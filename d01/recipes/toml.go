@@ -0,0 +1,92 @@
+package recipes
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+func init() {
+	Register(NewCodec("toml", []string{".toml"}, sniffTOML, readTOML, writeTOML))
+}
+
+// RecipesTOML follows the JSON schema key for key: every cake is a [[cake]]
+// table and every ingredient a [[cake.ingredients]] table under it.
+type RecipesTOML struct {
+	Cake []CakeTOML `toml:"cake"`
+}
+
+type CakeTOML struct {
+	Name        string           `toml:"name"`
+	Time        tomlText         `toml:"time"`
+	Ingredients []IngredientTOML `toml:"ingredients"`
+}
+
+type IngredientTOML struct {
+	IngredientName  string   `toml:"ingredient_name"`
+	IngredientCount tomlText `toml:"ingredient_count"`
+	IngredientUnit  string   `toml:"ingredient_unit,omitempty"`
+}
+
+// tomlText is a value written as a string, which people writing TOML by
+// hand may give as a number instead: time = 45 or ingredient_count = 3.
+type tomlText string
+
+func (text *tomlText) UnmarshalTOML(value any) error {
+	switch value := value.(type) {
+	case string:
+		*text = tomlText(value)
+	case int64:
+		*text = tomlText(strconv.FormatInt(value, 10))
+	case float64:
+		*text = tomlText(strconv.FormatFloat(value, 'f', -1, 64))
+	default:
+		return fmt.Errorf("want a string or a number, not %T", value)
+	}
+	return nil
+}
+
+func sniffTOML(head []byte) bool {
+	return startsWith(head, "[[cake]]")
+}
+
+func readTOML(r io.Reader) (map[string]Cake, error) {
+	var data RecipesTOML
+	if _, err := toml.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	cakes := make(map[string]Cake)
+	for _, cake := range data.Cake {
+		entry := Cake{Time: string(cake.Time), IngredientMap: make(map[string]Ingredient)}
+		for _, ingredient := range cake.Ingredients {
+			entry.IngredientMap[ingredient.IngredientName] = Ingredient{
+				IngredientCount: string(ingredient.IngredientCount),
+				IngredientUnit:  ingredient.IngredientUnit,
+			}
+		}
+		cakes[cake.Name] = entry
+	}
+	return cakes, nil
+}
+
+func writeTOML(w io.Writer, cakes map[string]Cake) error {
+	data := RecipesTOML{Cake: make([]CakeTOML, 0, len(cakes))}
+	for _, name := range cakeNames(cakes) {
+		cake := cakes[name]
+		entry := CakeTOML{Name: name, Time: tomlText(cake.Time), Ingredients: make([]IngredientTOML, 0, len(cake.IngredientMap))}
+		for _, ingredientName := range ingredientNames(cake) {
+			ingredient := cake.IngredientMap[ingredientName]
+			entry.Ingredients = append(entry.Ingredients, IngredientTOML{
+				IngredientName:  ingredientName,
+				IngredientCount: tomlText(ingredient.IngredientCount),
+				IngredientUnit:  ingredient.IngredientUnit,
+			})
+		}
+		data.Cake = append(data.Cake, entry)
+	}
+	encoder := toml.NewEncoder(w)
+	encoder.Indent = ""
+	return encoder.Encode(data)
+}
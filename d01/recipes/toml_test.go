@@ -0,0 +1,36 @@
+package recipes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadTOMLAcceptsNumbers(t *testing.T) {
+	input := `[[cake]]
+name = "A"
+time = 45
+
+[[cake.ingredients]]
+ingredient_name = "Egg"
+ingredient_count = 3
+
+[[cake.ingredients]]
+ingredient_name = "Oil"
+ingredient_count = 0.5
+ingredient_unit = "cup"
+`
+	cakes, err := readTOML(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Cake{Time: "45", IngredientMap: map[string]Ingredient{
+		"Egg": {IngredientCount: "3"},
+		"Oil": {IngredientCount: "0.5", IngredientUnit: "cup"},
+	}}
+	if !sameCake(cakes["A"], want) {
+		t.Errorf("read %+v, want %+v", cakes["A"], want)
+	}
+	if _, err := readTOML(strings.NewReader("[[cake]]\nname = \"A\"\ntime = true\n")); err == nil {
+		t.Error("a boolean time was read")
+	}
+}
@@ -0,0 +1,74 @@
+package recipes
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register(NewCodec("xml", []string{".xml"}, sniffXML, readXML, writeXML))
+}
+
+type RecipesXML struct {
+	XMLName xml.Name  `xml:"recipes"`
+	Cake    []CakeXML `xml:"cake"`
+}
+
+type CakeXML struct {
+	Name        string `xml:"name"`
+	Stovetime   string `xml:"stovetime"`
+	Ingredients struct {
+		Item []ItemXML `xml:"item"`
+	} `xml:"ingredients"`
+}
+
+type ItemXML struct {
+	Itemname  string `xml:"itemname"`
+	Itemcount string `xml:"itemcount"`
+	Itemunit  string `xml:"itemunit,omitempty"`
+}
+
+func sniffXML(head []byte) bool {
+	return startsWith(head, "<")
+}
+
+func readXML(r io.Reader) (map[string]Cake, error) {
+	var data RecipesXML
+	if err := xml.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	cakes := make(map[string]Cake)
+	for _, cake := range data.Cake {
+		entry := Cake{Time: cake.Stovetime, IngredientMap: make(map[string]Ingredient)}
+		for _, item := range cake.Ingredients.Item {
+			entry.IngredientMap[item.Itemname] = Ingredient{IngredientCount: item.Itemcount, IngredientUnit: item.Itemunit}
+		}
+		cakes[cake.Name] = entry
+	}
+	return cakes, nil
+}
+
+func writeXML(w io.Writer, cakes map[string]Cake) error {
+	data := RecipesXML{Cake: make([]CakeXML, 0, len(cakes))}
+	for _, name := range cakeNames(cakes) {
+		cake := cakes[name]
+		entry := CakeXML{Name: name, Stovetime: cake.Time}
+		for _, ingredientName := range ingredientNames(cake) {
+			ingredient := cake.IngredientMap[ingredientName]
+			entry.Ingredients.Item = append(entry.Ingredients.Item, ItemXML{
+				Itemname:  ingredientName,
+				Itemcount: ingredient.IngredientCount,
+				Itemunit:  ingredient.IngredientUnit,
+			})
+		}
+		data.Cake = append(data.Cake, entry)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "    ")
+	if err := encoder.Encode(data); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
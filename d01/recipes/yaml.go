@@ -0,0 +1,75 @@
+package recipes
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	Register(NewCodec("yaml", []string{".yaml", ".yml"}, sniffYAML, readYAML, writeYAML))
+}
+
+// RecipesYAML follows the JSON schema key for key, so a database converts
+// between the two without losing anything.
+type RecipesYAML struct {
+	Cake []CakeYAML `yaml:"cake"`
+}
+
+type CakeYAML struct {
+	Name        string           `yaml:"name"`
+	Time        string           `yaml:"time"`
+	Ingredients []IngredientYAML `yaml:"ingredients"`
+}
+
+type IngredientYAML struct {
+	IngredientName  string `yaml:"ingredient_name"`
+	IngredientCount string `yaml:"ingredient_count"`
+	IngredientUnit  string `yaml:"ingredient_unit,omitempty"`
+}
+
+func sniffYAML(head []byte) bool {
+	return startsWith(head, "---") || startsWith(head, "cake:")
+}
+
+func readYAML(r io.Reader) (map[string]Cake, error) {
+	var data RecipesYAML
+	if err := yaml.NewDecoder(r).Decode(&data); err != nil && err != io.EOF {
+		return nil, err
+	}
+	cakes := make(map[string]Cake)
+	for _, cake := range data.Cake {
+		entry := Cake{Time: cake.Time, IngredientMap: make(map[string]Ingredient)}
+		for _, ingredient := range cake.Ingredients {
+			entry.IngredientMap[ingredient.IngredientName] = Ingredient{
+				IngredientCount: ingredient.IngredientCount,
+				IngredientUnit:  ingredient.IngredientUnit,
+			}
+		}
+		cakes[cake.Name] = entry
+	}
+	return cakes, nil
+}
+
+func writeYAML(w io.Writer, cakes map[string]Cake) error {
+	data := RecipesYAML{Cake: make([]CakeYAML, 0, len(cakes))}
+	for _, name := range cakeNames(cakes) {
+		cake := cakes[name]
+		entry := CakeYAML{Name: name, Time: cake.Time, Ingredients: make([]IngredientYAML, 0, len(cake.IngredientMap))}
+		for _, ingredientName := range ingredientNames(cake) {
+			ingredient := cake.IngredientMap[ingredientName]
+			entry.Ingredients = append(entry.Ingredients, IngredientYAML{
+				IngredientName:  ingredientName,
+				IngredientCount: ingredient.IngredientCount,
+				IngredientUnit:  ingredient.IngredientUnit,
+			})
+		}
+		data.Cake = append(data.Cake, entry)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(4)
+	if err := encoder.Encode(data); err != nil {
+		return err
+	}
+	return encoder.Close()
+}